@@ -23,6 +23,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 
 	"github.com/roryq/wrench/internal/fs"
@@ -60,6 +61,14 @@ func apply(c *cobra.Command, _ []string) error {
 		}
 	}
 
+	placeholders, err := userPlaceholders(c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
 	placeholderOptions := spanner.PlaceholderOptions{
 		Placeholders: core.DefaultPlaceholders(
 			c.Flag(flagNameProject).Value.String(),
@@ -68,6 +77,7 @@ func apply(c *cobra.Command, _ []string) error {
 		),
 		ReplacementEnabled: placeholderReplacement,
 	}
+	maps.Copy(placeholderOptions.Placeholders, placeholders)
 
 	if ddlFile != "" {
 		if dmlFile != "" {
@@ -135,6 +145,8 @@ func init() {
 	applyCmd.PersistentFlags().StringVar(&ddlFile, flagDDLFile, "", "DDL file to be applied")
 	applyCmd.PersistentFlags().StringVar(&dmlFile, flagDMLFile, "", "DML file to be applied")
 	applyCmd.PersistentFlags().BoolVar(&partitioned, flagPartitioned, false, "Whether given DML should be executed as a Partitioned-DML or not")
-	applyCmd.Flags().Bool(flagPlaceholderReplacement, true, "Enable placeholder replacement for ${PROJECT_ID}, ${INSTANCE_ID} and ${DATABASE_ID}")
+	applyCmd.Flags().Bool(flagPlaceholderReplacement, true, "Enable placeholder replacement for ${PROJECT_ID}, ${INSTANCE_ID}, ${DATABASE_ID} and user-defined placeholders")
+	applyCmd.Flags().StringArray(flagPlaceholder, nil, "Placeholder value as KEY=VALUE, can be repeated. Takes precedence over $WRENCH_PLACEHOLDER_KEY and the placeholders file")
+	applyCmd.Flags().String(flagPlaceholdersFile, "", "JSON file of placeholder values (optional. if not set, will use 'placeholders.<environment>.json' or 'placeholders.json' if it exists)")
 	applyCmd.PersistentFlags().String(flagProtoDescriptorFile, "", "Proto descriptor file to be used with DDL operations")
 }
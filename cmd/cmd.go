@@ -20,7 +20,9 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
+	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -52,17 +54,49 @@ const (
 	flagPlaceholderReplacement    = "placeholder-replacement"
 	flagProtoDescriptorFile       = "proto-descriptor-file"
 	flagFFMigrations              = "ff-migrations"
+	flagProgressInterval          = "progress-interval"
+	flagWriteRemaining            = "write-remaining"
+	flagRetryAttempts             = "retry-attempts"
+	flagRetryBackoff              = "retry-backoff"
+	flagEnvironment               = "environment"
+	flagPlaceholder               = "placeholder"
+	flagPlaceholdersFile          = "placeholders-file"
+	flagSeed                      = "seed"
+	flagPruneRepeatables          = "prune-repeatables"
+	flagStream                    = "stream"
+	flagOutOfOrder                = "out-of-order"
+	flagUpTo                      = "up-to"
+	flagArchiveDir                = "archive-dir"
+	flagBase                      = "base"
+	flagAfter                     = "after"
+	flagSequenceStrategy          = "sequence-strategy"
+	flagTemplate                  = "template"
+	flagRepeatable                = "repeatable"
+	flagMigrationsDir             = "migrations-dir"
+	flagSubdir                    = "subdir"
+	flagEnv                       = "env"
+	flagVersionTable              = "version-table"
+	flagLockTable                 = "lock-table"
+	flagDatabasesFile             = "databases-file"
+	flagDatabaseGlob              = "database-glob"
+	flagInstances                 = "instances"
+	flagConcurrency               = "concurrency"
+	flagContinueOnError           = "continue-on-error"
+	flagReport                    = "report"
+	flagRehearse                  = "rehearse"
+	flagRehearseStaticData        = "rehearse-static-data"
+	flagAtVersion                 = "at-version"
+	flagDiffVersions              = "diff-versions"
+	flagVersionsOutputDir         = "versions-output-dir"
 	defaultSchemaFileName         = "schema.sql"
 	defaultStaticDataTablesFile   = "{wrench.json|static_data_tables.txt}"
+	defaultTemplatesDir           = "templates"
 )
 
 func newSpannerClient(ctx context.Context, c *cobra.Command) (*spanner.Client, error) {
-	config := &spanner.Config{
-		Project:         c.Flag(flagNameProject).Value.String(),
-		Instance:        c.Flag(flagNameInstance).Value.String(),
-		Database:        c.Flag(flagNameDatabase).Value.String(),
-		CredentialsFile: c.Flag(flagCredentialsFile).Value.String(),
-		StmtTimeout:     stmtTimeout,
+	config, err := spannerConfig(c)
+	if err != nil {
+		return nil, err
 	}
 
 	client, err := spanner.NewClient(ctx, config)
@@ -76,6 +110,31 @@ func newSpannerClient(ctx context.Context, c *cobra.Command) (*spanner.Client, e
 	return client, nil
 }
 
+// spannerConfig returns the client config of the database set by the flags.
+func spannerConfig(c *cobra.Command) (*spanner.Config, error) {
+	outOfOrderPolicy, err := spanner.ParseOutOfOrderPolicy(outOfOrder)
+	if err != nil {
+		return nil, &Error{
+			err: err,
+			cmd: c,
+		}
+	}
+
+	return &spanner.Config{
+		Project:         c.Flag(flagNameProject).Value.String(),
+		Instance:        c.Flag(flagNameInstance).Value.String(),
+		Database:        c.Flag(flagNameDatabase).Value.String(),
+		CredentialsFile: c.Flag(flagCredentialsFile).Value.String(),
+		StmtTimeout:     stmtTimeout,
+		EventHandler:    newProgressReporter(os.Stderr, progressInterval).Handle,
+		RetryPolicy: spanner.RetryPolicy{
+			Attempts: int(retryAttempts),
+			Backoff:  retryBackoff,
+		},
+		OutOfOrder: outOfOrderPolicy,
+	}, nil
+}
+
 func outputDirPath(c *cobra.Command) string {
 	outDir := c.Flag(flagNameOutputDir).Value.String()
 	if outDir != "" {
@@ -100,6 +159,16 @@ func staticDataTablesFilePath(c *cobra.Command) string {
 	return filepath.Join(c.Flag(flagNameDirectory).Value.String(), filename)
 }
 
+// templatesDirPath returns the directory of project migration templates, set by TemplatesDirectory in wrench.json or
+// 'templates' in --directory.
+func templatesDirPath(c *cobra.Command) (string, error) {
+	config, err := readStaticDataTablesFile(staticDataTablesFilePath(c))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.Flag(flagNameDirectory).Value.String(), cmp.Or(config.TemplatesDirectory, defaultTemplatesDir)), nil
+}
+
 func protoDescriptorFilePath(c *cobra.Command) string {
 	var filename string
 
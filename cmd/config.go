@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const configFileName = "wrench.json"
+
+// environmentConfig is a named environment in wrench.json, selected with --env. Each setting is the default of the
+// matching flag, so flags and environment variables take precedence over the config file.
+type environmentConfig struct {
+	Project         string
+	Instance        string
+	Database        string
+	CredentialsFile string
+	// Environment is the environment of @wrench.Environments directives and placeholders files. Defaults to the name
+	// of the environment.
+	Environment               string
+	Placeholders              map[string]string
+	SkipVersions              []uint
+	DetectPartitionedDML      *bool
+	PartitionedDMLConcurrency uint16
+	// StmtTimeout is a duration, e.g. 10m.
+	StmtTimeout  string
+	VersionTable string
+	LockTable    string
+	// Databases are the databases migrated by fleet migrate, as database URLs or database IDs in the instance.
+	Databases []string
+}
+
+// selectedEnvironment is the environment selected with --env, if any.
+var selectedEnvironment environmentConfig
+
+// configFilePath returns wrench.json in the directory, or the static data tables file if it is a JSON file.
+func configFilePath(c *cobra.Command) string {
+	if filename := c.Flag(flagStaticDataTablesFile).Value.String(); strings.HasSuffix(filename, ".json") {
+		return filepath.Join(c.Flag(flagNameDirectory).Value.String(), filename)
+	}
+	return filepath.Join(c.Flag(flagNameDirectory).Value.String(), configFileName)
+}
+
+// readEnvironmentConfig returns the named environment of the config file.
+func readEnvironmentConfig(filePath, name string) (environmentConfig, error) {
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return environmentConfig{}, fmt.Errorf("environment %s not found as %s does not exist", name, filePath)
+	}
+
+	config, err := readJsonFile(filePath)
+	if err != nil {
+		return environmentConfig{}, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	env, ok := config.Environments[name]
+	if !ok {
+		names := slices.Sorted(maps.Keys(config.Environments))
+		return environmentConfig{}, fmt.Errorf("environment %s not found in %s, must be one of: %s", name, filePath, strings.Join(names, ", "))
+	}
+	env.Environment = cmp.Or(env.Environment, name)
+
+	return env, nil
+}
+
+// applyEnvironmentConfig selects the named environment of the config file, and sets each flag that was not set by a
+// flag or its environment variable to the value of the environment.
+func applyEnvironmentConfig(c *cobra.Command, name string) error {
+	selectedEnvironment = environmentConfig{}
+	if name == "" {
+		return nil
+	}
+
+	env, err := readEnvironmentConfig(configFilePath(c), name)
+	if err != nil {
+		return err
+	}
+	selectedEnvironment = env
+
+	var skipVersions []string
+	for _, v := range env.SkipVersions {
+		skipVersions = append(skipVersions, strconv.FormatUint(uint64(v), 10))
+	}
+	var detectPartitioned string
+	if env.DetectPartitionedDML != nil {
+		detectPartitioned = strconv.FormatBool(*env.DetectPartitionedDML)
+	}
+	var partitionedConcurrency string
+	if env.PartitionedDMLConcurrency > 0 {
+		partitionedConcurrency = strconv.FormatUint(uint64(env.PartitionedDMLConcurrency), 10)
+	}
+
+	settings := []struct {
+		flag    string
+		envVars []string
+		value   string
+	}{
+		{flagNameProject, []string{"SPANNER_PROJECT_ID", "GOOGLE_CLOUD_PROJECT"}, env.Project},
+		{flagNameInstance, []string{"SPANNER_INSTANCE_ID"}, env.Instance},
+		{flagNameDatabase, []string{"SPANNER_DATABASE_ID"}, env.Database},
+		{flagCredentialsFile, nil, env.CredentialsFile},
+		{flagEnvironment, []string{"WRENCH_ENVIRONMENT"}, env.Environment},
+		{flagSkipVersions, nil, strings.Join(skipVersions, ",")},
+		{flagDetectPartitionedDML, []string{"WRENCH_DETECT_PARTITIONED_DML"}, detectPartitioned},
+		{flagPartitionedDMLConcurrency, []string{"WRENCH_PARTITIONED_DML_CONCURRENCY"}, partitionedConcurrency},
+		{flagStmtTimeout, []string{"WRENCH_STATEMENT_TIMEOUT"}, env.StmtTimeout},
+		{flagVersionTable, []string{"WRENCH_VERSION_TABLE"}, env.VersionTable},
+		{flagLockTable, []string{"WRENCH_LOCK_TABLE"}, env.LockTable},
+	}
+
+	for _, s := range settings {
+		// flags only defined by some commands, e.g. --skip-versions, are skipped by the other commands
+		if s.value == "" || c.Flags().Lookup(s.flag) == nil || c.Flags().Changed(s.flag) {
+			continue
+		}
+		if slices.ContainsFunc(s.envVars, func(v string) bool { return os.Getenv(v) != "" }) {
+			continue
+		}
+		if err := c.Flags().Set(s.flag, s.value); err != nil {
+			return fmt.Errorf("environment %s: invalid %s: %w", name, s.flag, err)
+		}
+	}
+
+	return nil
+}
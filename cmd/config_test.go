@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyEnvironmentConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, configFileName), []byte(`{
+  "StaticDataTables": ["Countries"],
+  "Environments": {
+    "staging": {
+      "Project": "staging-project",
+      "Instance": "staging-instance",
+      "Database": "staging-db",
+      "Placeholders": {"REGION": "eu"},
+      "SkipVersions": [10, 20],
+      "DetectPartitionedDML": true,
+      "StmtTimeout": "10m"
+    }
+  }
+}`), 0o644))
+
+	newCommand := func() *cobra.Command {
+		c := &cobra.Command{}
+		c.Flags().String(flagNameDirectory, dir, "")
+		c.Flags().String(flagStaticDataTablesFile, "", "")
+		c.Flags().String(flagNameProject, "", "")
+		c.Flags().String(flagNameInstance, "", "")
+		c.Flags().String(flagNameDatabase, "", "")
+		c.Flags().String(flagEnvironment, "", "")
+		c.Flags().UintSlice(flagSkipVersions, nil, "")
+		c.Flags().Bool(flagDetectPartitionedDML, false, "")
+		c.Flags().Duration(flagStmtTimeout, 0, "")
+		return c
+	}
+	t.Cleanup(func() { selectedEnvironment = environmentConfig{} })
+
+	// flags take precedence over environment variables, which take precedence over the config file
+	t.Setenv("SPANNER_INSTANCE_ID", "env-instance")
+	c := newCommand()
+	require.NoError(t, c.Flags().Set(flagNameProject, "flag-project"))
+	require.NoError(t, applyEnvironmentConfig(c, "staging"))
+
+	assert.Equal(t, "flag-project", c.Flag(flagNameProject).Value.String())
+	assert.Equal(t, "", c.Flag(flagNameInstance).Value.String())
+	assert.Equal(t, "staging-db", c.Flag(flagNameDatabase).Value.String())
+	assert.Equal(t, "staging", c.Flag(flagEnvironment).Value.String())
+	skipVersions, err := c.Flags().GetUintSlice(flagSkipVersions)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{10, 20}, skipVersions)
+	timeout, err := c.Flags().GetDuration(flagStmtTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, timeout)
+	assert.Equal(t, "true", c.Flag(flagDetectPartitionedDML).Value.String())
+	assert.Equal(t, map[string]string{"REGION": "eu"}, selectedEnvironment.Placeholders)
+
+	err = applyEnvironmentConfig(newCommand(), "prod")
+	assert.ErrorContains(t, err, "environment prod not found in "+filepath.Join(dir, configFileName)+", must be one of: staging")
+
+	require.NoError(t, applyEnvironmentConfig(newCommand(), ""))
+	assert.Nil(t, selectedEnvironment.Placeholders)
+}
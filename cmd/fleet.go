@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roryq/wrench/internal/fs"
+	"github.com/roryq/wrench/internal/graceful"
+	"github.com/roryq/wrench/pkg/core"
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Manage a fleet of databases that share the same migrations",
+}
+
+var fleetMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply the migrations to each database of a fleet",
+	Long: `Apply the migrations to each database of a fleet, e.g. one database per tenant, like migrate up.
+
+The databases are read from --databases-file, one per line, from the Databases of the environment selected with --env,
+and from the databases of --instances whose ID matches --database-glob. Each database is a database URL,
+projects/PROJECT/instances/INSTANCE/databases/DATABASE, or a database ID in --project and --instance.
+
+Up to --concurrency databases are migrated at the same time, and each database takes its own migration lock. After a
+database fails the databases that have not started are skipped, unless --continue-on-error is set. The run ends with a
+summary of the versions before and after each database, and --report writes the summary as JSON. Each line of output
+and progress is prefixed with [INSTANCE/DATABASE].`,
+	RunE: fleetMigrate,
+}
+
+func init() {
+	rootCmd.AddCommand(fleetCmd)
+	fleetCmd.AddCommand(fleetMigrateCmd)
+
+	fleetMigrateCmd.Flags().String(flagDatabasesFile, "", "File of the databases to migrate, one database URL or database ID per line, relative to --directory")
+	fleetMigrateCmd.Flags().String(flagDatabaseGlob, "", "Migrate the databases of --instances whose ID matches the glob, e.g. 'tenant_*'")
+	fleetMigrateCmd.Flags().StringSlice(flagInstances, nil, "Instances listed by --database-glob (optional. if not set, will use --instance)")
+	fleetMigrateCmd.Flags().Int(flagConcurrency, 4, "Number of databases migrated at the same time")
+	fleetMigrateCmd.Flags().Bool(flagContinueOnError, false, "Continue to migrate the other databases after a database fails")
+	fleetMigrateCmd.Flags().String(flagReport, "", "File to write the JSON report of the versions and errors of each database to (optional)")
+	fleetMigrateCmd.Flags().UintSlice(flagSkipVersions, []uint{}, "Versions to skip during migration")
+	fleetMigrateCmd.Flags().Bool(flagPlaceholderReplacement, true, "Enable placeholder replacement for ${PROJECT_ID}, ${INSTANCE_ID}, ${DATABASE_ID} and user-defined placeholders")
+	fleetMigrateCmd.Flags().StringArray(flagPlaceholder, nil, "Placeholder value as KEY=VALUE, can be repeated. Takes precedence over $WRENCH_PLACEHOLDER_KEY and the placeholders file")
+	fleetMigrateCmd.Flags().String(flagPlaceholdersFile, "", "JSON file of placeholder values (optional. if not set, will use 'placeholders.<environment>.json' or 'placeholders.json' if it exists)")
+	fleetMigrateCmd.Flags().Bool(flagSeed, false, "Apply the seed data files that have changed after the migrations")
+	fleetMigrateCmd.Flags().String(flagProtoDescriptorFile, "", "Proto descriptor file to be used with migrations")
+	fleetMigrateCmd.Flags().Bool(flagFFMigrations, false, "Fast-forward migrations by batching contiguous DDL migrations into a request. Intended for dev environments.")
+}
+
+func fleetMigrate(c *cobra.Command, _ []string) error {
+	ctx := context.Background()
+
+	configs, err := fleetDatabases(ctx, c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+	if len(configs) == 0 {
+		return &Error{
+			cmd: c,
+			err: fmt.Errorf("no databases to migrate, set --%s, --%s or the Databases of --%s", flagDatabasesFile, flagDatabaseGlob, flagEnv),
+		}
+	}
+
+	toSkip, err := c.Flags().GetUintSlice(flagSkipVersions)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	concurrency, err := c.Flags().GetInt(flagConcurrency)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	continueOnError, err := c.Flags().GetBool(flagContinueOnError)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	placeholdersEnabled, err := c.Flags().GetBool(flagPlaceholderReplacement)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	placeholders, err := userPlaceholders(c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	applySeeds, err := c.Flags().GetBool(flagSeed)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	var protoDescriptor []byte
+	if protoDescriptorFile := protoDescriptorFilePath(c); protoDescriptorFile != "" {
+		protoDescriptor, err = fs.ReadFile(ctx, protoDescriptorFile)
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+	}
+
+	ffMigrations, err := c.Flags().GetBool(flagFFMigrations)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	// the first signal finishes the databases in progress, a second signal cancels them
+	stopFleet := make(chan struct{})
+	ctx, stop := graceful.StopThenCancel(ctx, func() {
+		fmt.Fprintln(os.Stderr, "Stopping after the databases in progress. Send the signal again to cancel them.")
+		close(stopFleet)
+	})
+	defer stop()
+
+	results, migrateErr := core.FleetMigrate(ctx, configs, streamMigrationsDir(c, stream),
+		core.WithFleetConcurrency(concurrency),
+		core.WithContinueOnError(continueOnError),
+		core.WithFleetStop(stopFleet),
+		core.WithSkipVersions(toSkip),
+		core.WithLockIdentifier(lockIdentifier),
+		core.WithVersionTable(streamVersionTable(stream)),
+		core.WithLockTable(lockTable),
+		core.WithPartitionedDMLConcurrency(partitionedDMLConcurrency),
+		core.WithDetectPartitionedDML(detectPartitionedDML),
+		core.WithPrintRowsAffected(verbose),
+		core.WithPlaceholdersEnabled(placeholdersEnabled),
+		core.WithPlaceholders(placeholders),
+		core.WithEnvironment(environment),
+		core.WithSeed(applySeeds),
+		core.WithProtoDescriptors(protoDescriptor),
+		core.WithFFMigrations(ffMigrations),
+	)
+
+	if results != nil {
+		fmt.Println()
+		core.PrintFleetResults(os.Stdout, results)
+	}
+
+	if reportPath := c.Flag(flagReport).Value.String(); reportPath != "" && results != nil {
+		report, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+		if err := os.WriteFile(reportPath, append(report, '\n'), 0o644); err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+	}
+
+	if migrateErr != nil {
+		return &Error{
+			cmd: c,
+			err: migrateErr,
+		}
+	}
+	return nil
+}
+
+// fleetDatabases returns the client configs of the databases in --databases-file, the Databases of the selected
+// environment and the databases matching --database-glob, without duplicates.
+func fleetDatabases(ctx context.Context, c *cobra.Command) ([]*spanner.Config, error) {
+	var entries []string
+	if filename := c.Flag(flagDatabasesFile).Value.String(); filename != "" {
+		lines, err := readDatabasesFile(filepath.Join(c.Flag(flagNameDirectory).Value.String(), filename))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, lines...)
+	}
+	entries = append(entries, selectedEnvironment.Databases...)
+
+	if glob := c.Flag(flagDatabaseGlob).Value.String(); glob != "" {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid --%s %q: %w", flagDatabaseGlob, glob, err)
+		}
+		instances, err := c.Flags().GetStringSlice(flagInstances)
+		if err != nil {
+			return nil, err
+		}
+		if len(instances) == 0 {
+			instances = []string{c.Flag(flagNameInstance).Value.String()}
+		}
+
+		project := c.Flag(flagNameProject).Value.String()
+		for _, instance := range instances {
+			ids, err := spanner.ListDatabases(ctx, project, instance, c.Flag(flagCredentialsFile).Value.String())
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				if ok, _ := path.Match(glob, id); ok {
+					entries = append(entries, fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, id))
+				}
+			}
+		}
+	}
+
+	base, err := spannerConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*spanner.Config
+	var outputMu sync.Mutex
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		config := *base
+		if strings.Contains(entry, "/") {
+			config.Project, config.Instance, config.Database, err = spanner.ParseDatabaseURL(entry)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			config.Database = entry
+		}
+		if seen[config.URL()] {
+			continue
+		}
+		seen[config.URL()] = true
+
+		// each database reports its own progress, and every line names the database
+		database := config.Instance + "/" + config.Database
+		config.EventHandler = newFleetProgressReporter(os.Stderr, progressInterval, database).Handle
+		config.Output = newPrefixWriter(&outputMu, os.Stdout, database)
+		configs = append(configs, &config)
+	}
+
+	return configs, nil
+}
+
+// readDatabasesFile returns the databases in the file, one per line. Blank lines and lines starting with # are
+// ignored.
+func readDatabasesFile(filePath string) ([]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var databases []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		databases = append(databases, line)
+	}
+
+	return databases, scanner.Err()
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fleetDatabases(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tenants.txt"), []byte(`# tenants in the default instance
+tenant_a
+
+projects/other-project/instances/eu/databases/tenant_b
+tenant_a
+`), 0o644))
+
+	c := &cobra.Command{}
+	c.Flags().String(flagNameDirectory, dir, "")
+	c.Flags().String(flagNameProject, "my-project", "")
+	c.Flags().String(flagNameInstance, "us", "")
+	c.Flags().String(flagNameDatabase, "", "")
+	c.Flags().String(flagCredentialsFile, "", "")
+	c.Flags().String(flagDatabasesFile, "tenants.txt", "")
+	c.Flags().String(flagDatabaseGlob, "", "")
+	c.Flags().StringSlice(flagInstances, nil, "")
+
+	selectedEnvironment = environmentConfig{Databases: []string{"tenant_c"}}
+	t.Cleanup(func() { selectedEnvironment = environmentConfig{} })
+
+	configs, err := fleetDatabases(context.Background(), c)
+	require.NoError(t, err)
+
+	var urls []string
+	for _, config := range configs {
+		urls = append(urls, config.URL())
+	}
+	assert.Equal(t, []string{
+		"projects/my-project/instances/us/databases/tenant_a",
+		"projects/other-project/instances/eu/databases/tenant_b",
+		"projects/my-project/instances/us/databases/tenant_c",
+	}, urls)
+
+	selectedEnvironment = environmentConfig{Databases: []string{"projects/my-project/databases/tenant_d"}}
+	_, err = fleetDatabases(context.Background(), c)
+	assert.ErrorContains(t, err, "invalid database URL")
+}
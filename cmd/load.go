@@ -43,6 +43,11 @@ const (
 type staticDataConfig struct {
 	StaticDataTables []string
 	CustomOrderBy    map[string]string
+	// TemplatesDirectory is the directory of project migration templates, relative to --directory. Only read from
+	// wrench.json.
+	TemplatesDirectory string
+	// Environments are the named environments selected with --env. Only read from wrench.json.
+	Environments map[string]environmentConfig
 }
 
 var loadCmd = &cobra.Command{
@@ -25,7 +25,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -33,6 +32,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/roryq/wrench/internal/fs"
+	"github.com/roryq/wrench/internal/graceful"
 
 	"github.com/roryq/wrench/pkg/core"
 	"github.com/roryq/wrench/pkg/spanner"
@@ -54,7 +54,17 @@ func init() {
 	migrateCreateCmd := &cobra.Command{
 		Use:   "create NAME",
 		Short: "Create a set of sequential up migrations in directory",
-		RunE:  migrateCreate,
+		Long: `Create a set of sequential up migrations in directory.
+
+With --template the migration starts with the directives and example statements of the template. The built-in templates
+are ddl, dml, partitioned, convergent and repeatable. Project templates are read from NAME.sql in the 'templates'
+directory, or the TemplatesDirectory set in wrench.json, and take precedence over the built-in templates.
+
+With --repeatable, or the repeatable template, a repeatable migration R__NAME.sql is created instead.
+
+With --subdir the migration is created in a subdirectory of the migrations directory, e.g. 2026. Migrations are
+loaded from all subdirectories except 'archive', and the version follows the latest migration in any of them.`,
+		RunE: migrateCreate,
 	}
 	migrateUpCmd := &cobra.Command{
 		Use:   "up [N]",
@@ -73,7 +83,31 @@ func init() {
 	migrateRepairCmd := &cobra.Command{
 		Use:   "repair",
 		Short: "If a migration has failed, clean up any schema changes manually then repair the history with this command",
-		RunE:  migrateRepair,
+		Long: `If a migration has failed, clean up any schema changes manually then repair the history with this command.
+
+When a DDL migration fails, Spanner has already committed the statements before the failing statement. The repair
+prints which statements of the dirty migrations were applied and which were not. With --write-remaining the statements
+that were not applied are written to a new migration, and the dirty versions are marked as applied instead of removed.`,
+		RunE: migrateRepair,
+	}
+	migrateWaitCmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for the DDL operation of an interrupted migration to complete, then mark the version clean",
+		RunE:  migrateWait,
+	}
+	migrateStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Print the status of each migration file",
+		Long: `Print the status of each migration file: applied, skipped, dirty or pending.
+
+Migrations scoped to environments with the @wrench.Environments directive are recorded as skipped in the history when
+migrating any other environment (set with --environment). Pending migrations that will be skipped show the reason.
+
+To switch from sequential to timestamp versions, set --sequence-strategy=timestamp (or $WRENCH_SEQUENCE_STRATEGY) for
+migrate create. Existing migrations keep their versions and no files are renamed, as timestamp versions such as
+20240102150405 sort after sequential versions. A timestamp migration from a branch may be merged after a later one has
+been applied, so keep --out-of-order=allow or warn, and use wrench.sum to catch collisions.`,
+		RunE: migrateStatus,
 	}
 	migrateHistoryCmd := &cobra.Command{
 		Use:   "history",
@@ -91,37 +125,87 @@ func init() {
 		migrateCreateCmd,
 		migrateUpCmd,
 		migrateVersionCmd,
+		migrateStatusCmd,
 		migrateSetCmd,
 		migrateHistoryCmd,
 		migrateLockerCmd,
 		migrateRepairCmd,
+		migrateWaitCmd,
 	)
 
 	migrateCreateCmd.Flags().SetNormalizeFunc(underscoreToDashes)
 	migrateUpCmd.Flags().SetNormalizeFunc(underscoreToDashes)
 
 	migrateCreateCmd.Flags().Bool(flagNameCreateNoPrompt, false, "Don't prompt for a migration file description")
+	migrateCreateCmd.Flags().String(flagTemplate, "", "Template of the migration: ddl, dml, partitioned, convergent, repeatable or a project template (optional. if not set, the migration is empty)")
+	migrateCreateCmd.Flags().Bool(flagRepeatable, false, "Create a repeatable migration R__NAME.sql")
+	migrateCreateCmd.Flags().String(flagSubdir, "", "Subdirectory of the migrations directory to create the migration in, e.g. 2026 (optional)")
 	migrateUpCmd.Flags().UintSlice(flagSkipVersions, []uint{}, "Versions to skip during migration")
-	migrateUpCmd.Flags().Bool(flagPlaceholderReplacement, true, "Enable placeholder replacement for ${PROJECT_ID}, ${INSTANCE_ID} and ${DATABASE_ID}")
+	migrateStatusCmd.Flags().UintSlice(flagSkipVersions, []uint{}, "Versions to skip during migration")
+	migrateUpCmd.Flags().Bool(flagPlaceholderReplacement, true, "Enable placeholder replacement for ${PROJECT_ID}, ${INSTANCE_ID}, ${DATABASE_ID} and user-defined placeholders")
+	migrateUpCmd.Flags().StringArray(flagPlaceholder, nil, "Placeholder value as KEY=VALUE, can be repeated. Takes precedence over $WRENCH_PLACEHOLDER_KEY and the placeholders file")
+	migrateUpCmd.Flags().String(flagPlaceholdersFile, "", "JSON file of placeholder values (optional. if not set, will use 'placeholders.<environment>.json' or 'placeholders.json' if it exists)")
 	migrateUpCmd.Flags().String(flagProtoDescriptorFile, "", "Proto descriptor file to be used with migrations")
+	migrateRepairCmd.Flags().Bool(flagWriteRemaining, false, "Write the statements not applied by a failed DDL migration to a new migration and mark the dirty versions as applied")
+	migrateUpCmd.Flags().Bool(flagSeed, false, "Apply the seed data files that have changed after the migrations")
+	migrateUpCmd.Flags().Bool(flagPruneRepeatables, false, "Delete the history of repeatable migrations whose file has been deleted")
+	migrateUpCmd.Flags().Bool(flagRehearse, false, "Rehearse the pending migrations on a shadow database in a dockerised emulator, created from the schema of the database, before applying them. (Requires docker)")
+	migrateUpCmd.Flags().Bool(flagRehearseStaticData, false, "Copy the static data tables to the shadow database of --rehearse")
+	migrateUpCmd.Flags().String(flagSpannerEmulatorImage, "roryq/spanner-emulator:latest", "Spanner emulator image used by --rehearse. Override this to pin version or change registry.")
 	migrateUpCmd.Flags().Bool(flagFFMigrations, false, "Fast-forward migrations by batching contiguous DDL migrations into a request. Intended for dev environments.")
 }
 
 func migrateCreate(c *cobra.Command, args []string) error {
 	name := getNameForMigration(c, args)
 
-	dir := filepath.Join(c.Flag(flagNameDirectory).Value.String(), migrationsDirName)
+	dir := streamMigrationsDir(c, stream)
+
+	for _, d := range spanner.MigrationsDirs(dir) {
+		if _, err := os.Stat(d); os.IsNotExist(err) {
+			if err := os.MkdirAll(d, os.ModePerm); err != nil {
+				return &Error{
+					cmd: c,
+					err: err,
+				}
+			}
+		}
+	}
+
+	repeatable, err := c.Flags().GetBool(flagRepeatable)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
 
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.Mkdir(dir, os.ModePerm); err != nil {
+	var template string
+	if templateName := c.Flag(flagTemplate).Value.String(); templateName != "" {
+		templatesDir, err := templatesDirPath(c)
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+		template, err = core.MigrationTemplate(templatesDir, templateName)
+		if err != nil {
 			return &Error{
 				cmd: c,
 				err: err,
 			}
 		}
+		repeatable = repeatable || templateName == core.TemplateRepeatable
 	}
 
-	filename, err := core.CreateMigrationFile(dir, name, core.WithInterval(sequenceInterval), core.WithZeroPrefixLength(6))
+	filename, err := core.CreateMigrationFile(dir, name,
+		core.WithInterval(sequenceInterval),
+		core.WithZeroPrefixLength(6),
+		core.WithSequenceStrategy(core.SequenceStrategy(sequenceStrategy)),
+		core.WithTemplate(template),
+		core.WithRepeatable(repeatable),
+		core.WithSubdirectory(c.Flag(flagSubdir).Value.String()),
+	)
 	if err != nil {
 		return &Error{
 			cmd: c,
@@ -148,6 +232,11 @@ func getNameForMigration(c *cobra.Command, args []string) string {
 }
 
 func migrateUp(c *cobra.Command, args []string) error {
+	return migrateUpStream(c, args, stream)
+}
+
+// migrateUpStream applies the migrations of the stream, with the extra options after the options set by the flags.
+func migrateUpStream(c *cobra.Command, args []string, stream string, extra ...core.MigrateOpt) error {
 	ctx := context.Background()
 
 	limit := -1
@@ -184,6 +273,14 @@ func migrateUp(c *cobra.Command, args []string) error {
 		}
 	}
 
+	placeholders, err := userPlaceholders(c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
 	var protoDescriptor []byte
 	protoDescriptorFile := protoDescriptorFilePath(c)
 	if protoDescriptorFile != "" {
@@ -204,13 +301,29 @@ func migrateUp(c *cobra.Command, args []string) error {
 		}
 	}
 
-	migrationsDir := filepath.Join(c.Flag(flagNameDirectory).Value.String(), migrationsDirName)
-	err = core.MigrateUp(ctx, client, migrationsDir,
+	applySeeds, err := c.Flags().GetBool(flagSeed)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	pruneRepeatables, err := c.Flags().GetBool(flagPruneRepeatables)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	migrationsDir := streamMigrationsDir(c, stream)
+	opts := []core.MigrateOpt{
 		core.WithLimit(limit),
 		core.WithSkipVersions(toSkip),
 		core.WithLockIdentifier(lockIdentifier),
-		core.WithVersionTable(migrationTableName),
-		core.WithLockTable(migrationLockTable),
+		core.WithVersionTable(streamVersionTable(stream)),
+		core.WithLockTable(lockTable),
 		core.WithPartitionedDMLConcurrency(partitionedDMLConcurrency),
 		core.WithDetectPartitionedDML(detectPartitionedDML),
 		core.WithPrintRowsAffected(verbose),
@@ -220,10 +333,32 @@ func migrateUp(c *cobra.Command, args []string) error {
 			c.Flag(flagNameInstance).Value.String(),
 			c.Flag(flagNameDatabase).Value.String(),
 		),
+		core.WithPlaceholders(placeholders),
 		core.WithProtoDescriptors(protoDescriptor),
 		core.WithFFMigrations(ffMigrations),
-	)
+		core.WithEnvironment(environment),
+		core.WithSeed(applySeeds),
+		core.WithPruneRepeatables(pruneRepeatables),
+	}
+	opts = append(opts, extra...)
 
+	if rehearse, _ := c.Flags().GetBool(flagRehearse); rehearse {
+		if err := rehearseMigrations(ctx, c, client, migrationsDir, opts); err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+	}
+
+	// the first signal stops before the next migration, a second signal cancels the migration in progress
+	ctx, stop := graceful.StopThenCancel(ctx, func() {
+		fmt.Fprintln(os.Stderr, "Stopping after the current migration. Send the signal again to cancel it.")
+		client.StopAfterCurrentMigration()
+	})
+	defer stop()
+
+	err = core.MigrateUp(ctx, client, migrationsDir, opts...)
 	if err != nil {
 		return &Error{
 			cmd: c,
@@ -242,14 +377,14 @@ func migrateVersion(c *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	if err = client.EnsureMigrationTable(ctx, migrationTableName); err != nil {
+	if err = client.EnsureMigrationTable(ctx, streamVersionTable(stream), lockTable); err != nil {
 		return &Error{
 			cmd: c,
 			err: err,
 		}
 	}
 
-	v, _, err := client.GetSchemaMigrationVersion(ctx, migrationTableName)
+	v, _, err := client.GetSchemaMigrationVersion(ctx, streamVersionTable(stream))
 	if err != nil {
 		var se *spanner.Error
 		if errors.As(err, &se) && se.Code == spanner.ErrorCodeNoMigration {
@@ -276,7 +411,53 @@ func migrateHistory(c *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	err = core.MigrateHistory(ctx, client, core.WithLockTable(migrationLockTable), core.WithLockIdentifier(lockIdentifier))
+	names, err := allStreams(c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	for i, name := range names {
+		if len(names) > 1 {
+			printStreamHeader(i, name)
+		}
+
+		err = core.MigrateHistory(ctx, client,
+			core.WithLockTable(lockTable),
+			core.WithLockIdentifier(lockIdentifier),
+			core.WithVersionTable(streamVersionTable(name)),
+		)
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+	}
+
+	return nil
+}
+
+func migrateStatus(c *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	toSkip, err := c.Flags().GetUintSlice(flagSkipVersions)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	client, err := newSpannerClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	names, err := allStreams(c)
 	if err != nil {
 		return &Error{
 			cmd: c,
@@ -284,9 +465,35 @@ func migrateHistory(c *cobra.Command, args []string) error {
 		}
 	}
 
+	for i, name := range names {
+		if len(names) > 1 {
+			printStreamHeader(i, name)
+		}
+
+		err = core.MigrateStatus(ctx, client, streamMigrationsDir(c, name),
+			core.WithLockTable(lockTable),
+			core.WithLockIdentifier(lockIdentifier),
+			core.WithVersionTable(streamVersionTable(name)),
+			core.WithSkipVersions(toSkip),
+			core.WithEnvironment(environment),
+		)
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+	}
 	return nil
 }
 
+func printStreamHeader(i int, name string) {
+	if i > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("Stream: %s\n", streamLabel(name))
+}
+
 func migrateRepair(c *cobra.Command, args []string) error {
 	ctx := context.Background()
 
@@ -296,10 +503,42 @@ func migrateRepair(c *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
+	writeRemaining, err := c.Flags().GetBool(flagWriteRemaining)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
 	err = core.MigrateRepair(ctx, client,
-		core.WithLockTable(migrationLockTable),
+		core.WithLockTable(lockTable),
+		core.WithLockIdentifier(lockIdentifier),
+		core.WithVersionTable(streamVersionTable(stream)),
+		core.WithRepairReport(streamMigrationsDir(c, stream), writeRemaining),
+	)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+	return nil
+}
+
+func migrateWait(c *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	client, err := newSpannerClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = core.MigrateWait(ctx, client,
+		core.WithLockTable(lockTable),
 		core.WithLockIdentifier(lockIdentifier),
-		core.WithVersionTable(migrationTableName),
+		core.WithVersionTable(streamVersionTable(stream)),
 	)
 	if err != nil {
 		return &Error{
@@ -319,7 +558,7 @@ func migrateLocker(c *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	if err := core.MigrateSetupLock(ctx, client, core.WithLockTable(migrationLockTable)); err != nil {
+	if err := core.MigrateSetupLock(ctx, client, core.WithLockTable(lockTable)); err != nil {
 		return &Error{
 			cmd: c,
 			err: err,
@@ -22,9 +22,11 @@ package cmd_test
 import (
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/roryq/wrench/pkg/core"
+	"github.com/roryq/wrench/pkg/spanner"
 )
 
 func TestCreateMigrationFile(t *testing.T) {
@@ -63,6 +65,28 @@ func TestCreateMigrationFile(t *testing.T) {
 		})
 	}
 
+	t.Run("timestamp", func(t *testing.T) {
+		filename, err := core.CreateMigrationFile(testdatadir, "baz", core.WithSequenceStrategy(core.SequenceTimestamp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			_ = os.Remove(filename)
+		}()
+
+		// timestamp versions sort after the existing sequential versions
+		if !regexp.MustCompile(`^20[0-9]{12}_baz[.]sql$`).MatchString(filepath.Base(filename)) {
+			t.Errorf("filename want a YYYYMMDDHHMMSS version, but got %v", filename)
+		}
+	})
+
+	t.Run("invalid sequence strategy", func(t *testing.T) {
+		_, err := core.CreateMigrationFile(testdatadir, "baz", core.WithSequenceStrategy("random"))
+		if err == nil {
+			t.Error("err want invalid sequence strategy, but got nil")
+		}
+	})
+
 	t.Run("invalid name", func(t *testing.T) {
 		_, err := core.CreateMigrationFile(testdatadir, "あああ")
 		if err.Error() != "Invalid migration file name." {
@@ -70,3 +94,87 @@ func TestCreateMigrationFile(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateMigrationFileTemplates(t *testing.T) {
+	testcases := []struct {
+		template   string
+		repeatable bool
+		wantKind   spanner.StatementKind
+	}{
+		{template: "ddl", wantKind: spanner.StatementKindDDL},
+		{template: "dml", wantKind: spanner.StatementKindDML},
+		{template: "partitioned", wantKind: spanner.StatementKindPartitionedDML},
+		{template: "convergent", wantKind: spanner.StatementKindConvergentDML},
+		{template: "repeatable", repeatable: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.template, func(t *testing.T) {
+			dir := t.TempDir()
+			template, err := core.MigrationTemplate("", tc.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filename, err := core.CreateMigrationFile(dir, "example", core.WithTemplate(template), core.WithRepeatable(tc.repeatable))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ms, err := spanner.LoadMigrations(dir, nil, false, spanner.PlaceholderOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ms) != 1 {
+				t.Fatalf("migrations length want 1, but got %v", len(ms))
+			}
+			if ms[0].IsRepeatable != tc.repeatable {
+				t.Errorf("%s repeatable want %v, but got %v", filename, tc.repeatable, ms[0].IsRepeatable)
+			}
+			if tc.wantKind != "" && ms[0].Directives.StatementKind != tc.wantKind {
+				t.Errorf("statement kind want %v, but got %v", tc.wantKind, ms[0].Directives.StatementKind)
+			}
+		})
+	}
+
+	t.Run("project template", func(t *testing.T) {
+		templatesDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(templatesDir, "ddl.sql"), []byte("-- @wrench.Timeout=1h\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		template, err := core.MigrationTemplate(templatesDir, "ddl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if template != "-- @wrench.Timeout=1h\n" {
+			t.Errorf("project template should take precedence, but got %v", template)
+		}
+
+		if _, err := core.MigrationTemplate(templatesDir, "unknown"); err == nil {
+			t.Error("err want unknown migration template, but got nil")
+		}
+	})
+}
+
+func TestCreateMigrationFileSubdirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "2025"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2025", "000110_a.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	filename, err := core.CreateMigrationFile(dir, "b", core.WithSubdirectory("2026"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "2026", "000120_b.sql"); filename != want {
+		t.Errorf("filename want %v, but got %v", want, filename)
+	}
+
+	if _, err := core.CreateMigrationFile(dir, "c", core.WithSubdirectory("../outside")); err == nil {
+		t.Error("err want subdirectory must be inside the migrations directory, but got nil")
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const placeholderEnvPrefix = "WRENCH_PLACEHOLDER_"
+
+var placeholderNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// userPlaceholders returns the user-defined placeholders of the command. Flags take precedence over environment
+// variables, which take precedence over the placeholders file, then the placeholders of the environment selected with
+// --env.
+func userPlaceholders(c *cobra.Command) (map[string]string, error) {
+	flags, err := c.Flags().GetStringArray(flagPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath, required := placeholdersFilePath(c)
+	placeholders, err := loadPlaceholders(filePath, required, os.Environ(), flags)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range selectedEnvironment.Placeholders {
+		if !placeholderNameRegex.MatchString(k) {
+			return nil, fmt.Errorf("invalid placeholder name %q", k)
+		}
+		if _, ok := placeholders[k]; !ok {
+			placeholders[k] = v
+		}
+	}
+
+	return placeholders, nil
+}
+
+// placeholdersFilePath returns the placeholders file, and whether it was set explicitly. The default file is
+// placeholders.<environment>.json in the directory, or placeholders.json if no environment is set.
+func placeholdersFilePath(c *cobra.Command) (string, bool) {
+	if filename := c.Flag(flagPlaceholdersFile).Value.String(); filename != "" {
+		return filepath.Join(c.Flag(flagNameDirectory).Value.String(), filename), true
+	}
+
+	filename := "placeholders.json"
+	if environment != "" {
+		filename = fmt.Sprintf("placeholders.%s.json", environment)
+	}
+	return filepath.Join(c.Flag(flagNameDirectory).Value.String(), filename), false
+}
+
+func loadPlaceholders(filePath string, required bool, environ, flags []string) (map[string]string, error) {
+	placeholders := map[string]string{}
+
+	bytes, err := os.ReadFile(filePath)
+	if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(bytes, &placeholders); err != nil {
+			return nil, fmt.Errorf("failed to parse placeholders file %s: %w", filePath, err)
+		}
+	}
+
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, placeholderEnvPrefix) {
+			k, v, _ := strings.Cut(strings.TrimPrefix(kv, placeholderEnvPrefix), "=")
+			placeholders[k] = v
+		}
+	}
+
+	flagPlaceholders, err := parsePlaceholderFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(placeholders, flagPlaceholders)
+
+	for k := range placeholders {
+		if !placeholderNameRegex.MatchString(k) {
+			return nil, fmt.Errorf("invalid placeholder name %q", k)
+		}
+	}
+
+	return placeholders, nil
+}
+
+func parsePlaceholderFlags(flags []string) (map[string]string, error) {
+	placeholders := map[string]string{}
+	for _, kv := range flags {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid placeholder %q, expected KEY=VALUE", kv)
+		}
+		placeholders[k] = v
+	}
+	return placeholders, nil
+}
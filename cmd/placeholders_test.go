@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_loadPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "placeholders.dev.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"RETENTION": "1d", "ROLE": "file_role", "STREAM_V2": "file_stream"}`), 0o644))
+
+	tests := map[string]struct {
+		filePath string
+		required bool
+		environ  []string
+		flags    []string
+		want     map[string]string
+		wantErr  bool
+	}{
+		"flags take precedence over env and file": {
+			filePath: file,
+			environ:  []string{"WRENCH_PLACEHOLDER_ROLE=env_role", "WRENCH_PLACEHOLDER_STREAM_V2=env_stream", "HOME=/root"},
+			flags:    []string{"ROLE=flag_role", "EXPR=a=b"},
+			want: map[string]string{
+				"RETENTION": "1d",
+				"ROLE":      "flag_role",
+				"STREAM_V2": "env_stream",
+				"EXPR":      "a=b",
+			},
+		},
+		"missing default file is ignored": {
+			filePath: filepath.Join(dir, "placeholders.json"),
+			flags:    []string{"ROLE="},
+			want:     map[string]string{"ROLE": ""},
+		},
+		"missing explicit file": {
+			filePath: filepath.Join(dir, "placeholders.json"),
+			required: true,
+			wantErr:  true,
+		},
+		"flag without value": {
+			filePath: file,
+			flags:    []string{"ROLE"},
+			wantErr:  true,
+		},
+		"invalid name": {
+			filePath: file,
+			flags:    []string{"1ROLE=x"},
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := loadPlaceholders(tt.filePath, tt.required, tt.environ, tt.flags)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+const maxProgressStatementLength = 60
+
+// progressReporter renders migration progress events. On a terminal the progress is shown on a single line that is
+// updated in place, otherwise a log line is written at most once per interval.
+type progressReporter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	isTTY    bool
+	interval time.Duration
+	lastLog  time.Time
+	lastLine string
+	// prefix is written before each log line, e.g. the database of a fleet run.
+	prefix string
+}
+
+func newProgressReporter(f *os.File, interval time.Duration) *progressReporter {
+	return &progressReporter{
+		w:        f,
+		isTTY:    isTerminal(f),
+		interval: interval,
+	}
+}
+
+// newFleetProgressReporter returns a reporter that writes log lines prefixed with the database, as the progress of
+// databases migrated at the same time would overwrite each other on a single terminal line.
+func newFleetProgressReporter(w io.Writer, interval time.Duration, database string) *progressReporter {
+	return &progressReporter{
+		w:        w,
+		interval: interval,
+		prefix:   databasePrefix(database),
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *progressReporter) Handle(e spanner.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e.Kind {
+	case spanner.EventMigrationStarted:
+		r.lastLog = time.Time{}
+	case spanner.EventDDLProgress, spanner.EventDMLProgress:
+		line := formatProgress(e)
+		if r.isTTY {
+			if e.Done {
+				_, _ = fmt.Fprint(r.w, "\r\033[K")
+			} else {
+				_, _ = fmt.Fprintf(r.w, "\r\033[K%s", line)
+			}
+			return
+		}
+
+		// log when the current statement changes, otherwise once per interval
+		if e.Done || !r.statementChanged(line) && time.Since(r.lastLog) < r.interval {
+			return
+		}
+		r.lastLog = time.Now()
+		r.lastLine = line
+		_, _ = fmt.Fprintf(r.w, "%s %s%s\n", time.Now().Format(time.RFC3339), r.prefix, line)
+	}
+}
+
+func (r *progressReporter) statementChanged(line string) bool {
+	prefix, _, _ := strings.Cut(line, "]")
+	lastPrefix, _, _ := strings.Cut(r.lastLine, "]")
+	return prefix != lastPrefix
+}
+
+func formatProgress(e spanner.Event) string {
+	elapsed := e.Elapsed.Truncate(time.Second)
+	switch e.Kind {
+	case spanner.EventDDLProgress:
+		var throttled string
+		if e.Throttled {
+			throttled = " (throttled)"
+		}
+		return fmt.Sprintf("[%d/%d] %3d%% %s%s elapsed %v",
+			e.StatementIndex+1, e.StatementCount, e.ProgressPercent, shortStatement(e.Statement), throttled, elapsed)
+	case spanner.EventDMLProgress:
+		return fmt.Sprintf("[%d/%d statements] %d rows affected elapsed %v",
+			e.StatementIndex, e.StatementCount, e.RowsAffected, elapsed)
+	default:
+		return ""
+	}
+}
+
+// shortStatement collapses the statement onto a single line and truncates it to maxProgressStatementLength
+// characters, so that multi-byte characters are not split.
+func shortStatement(stmt string) string {
+	stmt = strings.Join(strings.Fields(stmt), " ")
+	if runes := []rune(stmt); len(runes) > maxProgressStatementLength {
+		return string(runes[:maxProgressStatementLength-3]) + "..."
+	}
+	return stmt
+}
+
+// databasePrefix returns the prefix of the output lines of the database.
+func databasePrefix(database string) string {
+	return "[" + database + "] "
+}
+
+// prefixWriter writes the prefix at the start of each line, so that the output of databases migrated at the same time
+// can be told apart.
+type prefixWriter struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+func newPrefixWriter(mu *sync.Mutex, w io.Writer, database string) *prefixWriter {
+	return &prefixWriter{
+		mu:     mu,
+		w:      w,
+		prefix: databasePrefix(database),
+	}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	// the writers of each database share the lock so that lines are not interleaved
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var buf strings.Builder
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		if !p.midLine {
+			buf.WriteString(p.prefix)
+		}
+		buf.WriteString(line)
+		p.midLine = !strings.HasSuffix(line, "\n")
+	}
+
+	if _, err := io.WriteString(p.w, buf.String()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+func Test_formatProgress(t *testing.T) {
+	tests := map[string]struct {
+		event spanner.Event
+		want  string
+	}{
+		"ddl": {
+			event: spanner.Event{
+				Kind:            spanner.EventDDLProgress,
+				Statement:       "CREATE INDEX IX_Singers_FirstName\n  ON Singers(FirstName)",
+				StatementIndex:  1,
+				StatementCount:  3,
+				ProgressPercent: 45,
+				Elapsed:         90*time.Second + 300*time.Millisecond,
+			},
+			want: "[2/3]  45% CREATE INDEX IX_Singers_FirstName ON Singers(FirstName) elapsed 1m30s",
+		},
+		"ddl throttled and truncated": {
+			event: spanner.Event{
+				Kind:           spanner.EventDDLProgress,
+				Statement:      "CREATE INDEX " + strings.Repeat("A", 100),
+				StatementCount: 1,
+				Throttled:      true,
+			},
+			want: "[1/1]   0% CREATE INDEX " + strings.Repeat("A", 44) + "... (throttled) elapsed 0s",
+		},
+		"dml": {
+			event: spanner.Event{
+				Kind:           spanner.EventDMLProgress,
+				StatementIndex: 1,
+				StatementCount: 2,
+				RowsAffected:   1234,
+				Elapsed:        5 * time.Second,
+			},
+			want: "[1/2 statements] 1234 rows affected elapsed 5s",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatProgress(tt.event))
+		})
+	}
+}
+
+func Test_shortStatement(t *testing.T) {
+	tests := map[string]struct {
+		stmt string
+		want string
+	}{
+		"collapsed onto a line": {
+			stmt: "CREATE INDEX SingersByName\n  ON Singers(Name)",
+			want: "CREATE INDEX SingersByName ON Singers(Name)",
+		},
+		"truncated": {
+			stmt: "UPDATE Singers SET Name = 'a' WHERE SingerID IN (SELECT SingerID FROM Albums)",
+			want: "UPDATE Singers SET Name = 'a' WHERE SingerID IN (SELECT S...",
+		},
+		"truncated by character": {
+			stmt: "UPDATE Singers SET Name = 'ビートルズのメンバー全員の名前を更新する' WHERE SingerID = '1'",
+			want: "UPDATE Singers SET Name = 'ビートルズのメンバー全員の名前を更新する' WHERE Si...",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := shortStatement(tt.stmt)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, utf8.ValidString(got))
+		})
+	}
+}
+
+func Test_progressReporter_logLines(t *testing.T) {
+	var buf bytes.Buffer
+	r := &progressReporter{w: &buf, interval: time.Hour}
+
+	ddl := func(index int, percent int32) spanner.Event {
+		return spanner.Event{Kind: spanner.EventDDLProgress, Statement: "CREATE INDEX", StatementIndex: index, StatementCount: 2, ProgressPercent: percent}
+	}
+
+	r.Handle(spanner.Event{Kind: spanner.EventMigrationStarted})
+	r.Handle(ddl(0, 10))
+	r.Handle(ddl(0, 20)) // within interval
+	r.Handle(ddl(1, 0))  // next statement
+	r.Handle(spanner.Event{Kind: spanner.EventDDLProgress, Done: true})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], "[1/2]  10% CREATE INDEX")
+		assert.Contains(t, lines[1], "[2/2]   0% CREATE INDEX")
+	}
+}
+
+func Test_fleetProgressReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newFleetProgressReporter(&buf, time.Hour, "instance/tenant_1")
+
+	r.Handle(spanner.Event{Kind: spanner.EventMigrationStarted})
+	r.Handle(spanner.Event{Kind: spanner.EventDDLProgress, Statement: "CREATE INDEX", StatementCount: 1, ProgressPercent: 10})
+
+	assert.False(t, r.isTTY)
+	assert.Contains(t, buf.String(), " [instance/tenant_1] [1/1]  10% CREATE INDEX elapsed")
+}
+
+func Test_prefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	a := newPrefixWriter(&mu, &buf, "instance/a")
+	b := newPrefixWriter(&mu, &buf, "instance/b")
+
+	_, _ = fmt.Fprint(a, "1/up\n2/")
+	_, _ = fmt.Fprint(b, "1/up\n")
+	_, _ = fmt.Fprint(a, "up\n\nno change\n")
+
+	assert.Equal(t, "[instance/a] 1/up\n[instance/a] 2/[instance/b] 1/up\nup\n[instance/a] \n[instance/a] no change\n", buf.String())
+}
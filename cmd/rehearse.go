@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roryq/wrench/pkg/core"
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// rehearseMigrations applies the pending migrations of the target database to a shadow database in a dockerised
+// emulator. The target client must be created before the emulator is started, as the emulator host is set for the
+// whole process. The emulator is removed before returning.
+func rehearseMigrations(ctx context.Context, c *cobra.Command, target *spanner.Client, migrationsDir string, opts []core.MigrateOpt) error {
+	defer gracefulSchemaTasks.Exit()
+
+	rehearseStaticData, err := c.Flags().GetBool(flagRehearseStaticData)
+	if err != nil {
+		return err
+	}
+	if rehearseStaticData {
+		config, err := readStaticDataTablesFile(staticDataTablesFilePath(c))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, core.WithStaticDataTables(config.StaticDataTables, config.CustomOrderBy))
+	}
+
+	if _, err := runSpannerEmulator(c.Flag(flagSpannerEmulatorImage).Value.String(), "project", "instance", "database"); err != nil {
+		return err
+	}
+
+	config, err := spannerConfig(c)
+	if err != nil {
+		return err
+	}
+	config.Project = "project"
+	config.Instance = "instance"
+	config.Database = "shadow"
+	config.CredentialsFile = ""
+
+	shadow, err := spanner.NewClient(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer shadow.Close()
+
+	return core.Rehearse(ctx, target, shadow, migrationsDir, opts...)
+}
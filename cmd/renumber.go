@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roryq/wrench/pkg/core"
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+var migrateRenumberCmd = &cobra.Command{
+	Use:   "renumber [FILE...]",
+	Short: "Renumber the local migrations to follow the latest version on the main line",
+	Long: `Renumber the local migrations to follow the latest version on the main line, e.g. when another branch merged a
+migration with the same version first.
+
+The local migrations are the migration files that do not exist at the --base git ref, unless the files are given as
+arguments relative to the migrations directory, e.g. 2026/000120_add_index.sql. They are renumbered in version order after the latest version at the base ref, or after --after, using the
+same --sequence-interval and --sequence-strategy as migrate create. Names and suffixes are preserved.
+
+If a database is set, applied versions are not reused, and renumbering fails if a local migration has an applied
+version. The history only records versions, so the database may have applied the local migration.`,
+	RunE: migrateRenumber,
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateRenumberCmd)
+
+	migrateRenumberCmd.Flags().String(flagBase, "origin/main", "Git ref of the main line, used to find the local migrations and the latest version")
+	migrateRenumberCmd.Flags().Uint(flagAfter, 0, "Renumber after this version instead of the latest version at the base ref")
+}
+
+func migrateRenumber(c *cobra.Command, args []string) error {
+	ctx := context.Background()
+	dir := streamMigrationsDir(c, stream)
+
+	after, err := c.Flags().GetUint(flagAfter)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	fileNames := args
+	if len(fileNames) == 0 || after == 0 {
+		baseFiles, err := gitListMigrationFiles(dir, c.Flag(flagBase).Value.String())
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+
+		if after == 0 {
+			for _, f := range baseFiles {
+				if v, ok := spanner.MigrationVersion(f); ok {
+					after = max(after, v)
+				}
+			}
+		}
+
+		if len(fileNames) == 0 {
+			fileNames, err = localMigrations(dir, baseFiles)
+			if err != nil {
+				return &Error{
+					cmd: c,
+					err: err,
+				}
+			}
+		}
+	}
+
+	if len(fileNames) == 0 {
+		fmt.Println("no local migrations to renumber")
+		return nil
+	}
+
+	applied, err := appliedVersions(ctx, c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	renumbered, err := core.RenumberMigrations(dir, fileNames, after, applied, core.WithInterval(sequenceInterval), core.WithZeroPrefixLength(6), core.WithSequenceStrategy(core.SequenceStrategy(sequenceStrategy)))
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	if len(renumbered) == 0 {
+		fmt.Println("no change")
+	}
+	for _, r := range renumbered {
+		fmt.Printf("%s -> %s\n", r.From, r.To)
+	}
+
+	return nil
+}
+
+// gitListFiles returns the paths of the files in dir and its subdirectories at the git ref, relative to dir. The
+// directory may not exist at the ref.
+func gitListFiles(dir, ref string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", dir, "ls-tree", "-r", "--name-only", ref, ".")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the migrations at %s: %w: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+// gitListMigrationFiles returns the files in the migrations directories at the git ref, relative to the first
+// directory like the file names given to core.RenumberMigrations.
+func gitListMigrationFiles(dir, ref string) ([]string, error) {
+	dirs := spanner.MigrationsDirs(dir)
+	var files []string
+	for _, d := range dirs {
+		fileNames, err := gitListFiles(d, ref)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fileNames {
+			rel, err := filepath.Rel(dirs[0], filepath.Join(d, filepath.FromSlash(f)))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, rel)
+		}
+	}
+
+	return files, nil
+}
+
+// localMigrations returns the versioned migrations in the migrations directories that are not in baseFiles, relative
+// to the first directory.
+func localMigrations(dir string, baseFiles []string) ([]string, error) {
+	base := make(map[string]bool, len(baseFiles))
+	for _, f := range baseFiles {
+		base[f] = true
+	}
+
+	files, err := spanner.ListMigrationFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var local []string
+	for _, f := range files {
+		rel, err := filepath.Rel(spanner.MigrationsDirs(dir)[0], f.Path())
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := spanner.MigrationVersion(f.FileName); ok && !base[rel] {
+			local = append(local, rel)
+		}
+	}
+
+	return local, nil
+}
+
+// appliedVersions returns the versions recorded in the history of the database, or nil if no database is set.
+func appliedVersions(ctx context.Context, c *cobra.Command) ([]uint, error) {
+	if c.Flag(flagNameDatabase).Value.String() == "" {
+		return nil, nil
+	}
+
+	client, err := newSpannerClient(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	history, err := client.GetMigrationHistory(ctx, streamVersionTable(stream))
+	var se *spanner.Error
+	if errors.As(err, &se) && se.Code == spanner.ErrorCodeGetMigrationVersion {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]uint, 0, len(history))
+	for _, h := range history {
+		applied = append(applied, uint(h.Version))
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/roryq/wrench/pkg/core"
+)
+
+func Test_renumber(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "migrations")
+	require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644))
+	}
+
+	// main has 110 and 120 from another branch, the local branch also created 120 with an assert file, and 130
+	git("init", "-q")
+	write("000110_a.sql")
+	write("000120_theirs.sql")
+	git("add", ".")
+	git("commit", "-q", "-m", "main")
+	write("000120_mine.tmpl.sql")
+	write("000120_mine.assert.sql")
+	write("000130_next.sql")
+
+	baseFiles, err := gitListFiles(dir, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"000110_a.sql", "000120_theirs.sql"}, baseFiles)
+
+	local, err := localMigrations(dir, baseFiles)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"000120_mine.tmpl.sql", "000130_next.sql"}, local)
+
+	// the history only records versions, so a local migration with an applied version may have been applied
+	_, err = core.RenumberMigrations(dir, local, 120, []uint{110, 130}, core.WithInterval(10), core.WithZeroPrefixLength(6))
+	assert.ErrorContains(t, err, "migration 000130_next.sql cannot be renumbered as version 130 is applied")
+
+	// 140 is applied in the database so it is passed over
+	renumbered, err := core.RenumberMigrations(dir, local, 120, []uint{110, 140}, core.WithInterval(10), core.WithZeroPrefixLength(6))
+	require.NoError(t, err)
+	assert.Equal(t, []core.Renumbered{
+		{From: "000120_mine.tmpl.sql", To: "000130_mine.tmpl.sql"},
+		{From: "000130_next.sql", To: "000150_next.sql"},
+	}, renumbered)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"000110_a.sql", "000120_theirs.sql", "000130_mine.assert.sql", "000130_mine.tmpl.sql", "000150_next.sql"}, names)
+}
@@ -20,7 +20,9 @@
 package cmd
 
 import (
+	"cmp"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +31,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+
+	"github.com/roryq/wrench/pkg/core"
 )
 
 var (
@@ -48,10 +52,21 @@ var (
 	staticDataTablesFile      string
 	lockIdentifier            string
 	sequenceInterval          uint16
+	sequenceStrategy          string
 	stmtTimeout               time.Duration
 	verbose                   bool
 	detectPartitionedDML      bool
 	partitionedDMLConcurrency uint16
+	progressInterval          time.Duration
+	retryAttempts             uint16
+	retryBackoff              time.Duration
+	environment               string
+	stream                    string
+	migrationsDirs            []string
+	outOfOrder                string
+	envName                   string
+	versionTable              string
+	lockTable                 string
 )
 
 var rootCmd = &cobra.Command{
@@ -68,6 +83,15 @@ func init() {
 	rootCmd.PersistentFlags().SetNormalizeFunc(underscoreToDashes)
 
 	rootCmd.SilenceUsage = true
+	rootCmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		if err := applyEnvironmentConfig(c, envName); err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+		return validateStream(stream)
+	}
 	rootCmd.SilenceErrors = true
 
 	rootCmd.AddCommand(createCmd)
@@ -78,6 +102,7 @@ func init() {
 	rootCmd.AddCommand(schemaCmd)
 	rootCmd.AddCommand(applyCmd)
 	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(seedCmd)
 	rootCmd.AddCommand(truncateCmd)
 
 	// global flags
@@ -91,10 +116,21 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&staticDataTablesFile, flagStaticDataTablesFile, "", "File containing list of static data tables to track (optional)")
 	rootCmd.PersistentFlags().StringVar(&lockIdentifier, flagLockIdentifier, getLockIdentifier(), "Random identifier used to lock migration operations to a single wrench process. (optional. if not set then it will be generated)")
 	rootCmd.PersistentFlags().Uint16Var(&sequenceInterval, flagSequenceInterval, getSequenceInterval(), "Used to generate the next migration id. Rounds up to the next interval. (optional. if not set, will use $WRENCH_SEQUENCE_INTERVAL or default to 1)")
+	rootCmd.PersistentFlags().StringVar(&sequenceStrategy, flagSequenceStrategy, cmp.Or(os.Getenv("WRENCH_SEQUENCE_STRATEGY"), string(core.SequenceSequential)), "Used to generate the next migration id: sequential, or timestamp for YYYYMMDDHHMMSS versions. (optional. if not set, will use $WRENCH_SEQUENCE_STRATEGY or default to sequential)")
 	rootCmd.PersistentFlags().BoolVar(&verbose, flagVerbose, false, "Used to indicate whether to output Migration information during a migration")
 	rootCmd.PersistentFlags().DurationVar(&stmtTimeout, flagStmtTimeout, getStmtTimeout(), "Set a non-default timeout for statement execution")
 	rootCmd.PersistentFlags().BoolVar(&detectPartitionedDML, flagDetectPartitionedDML, getDetectPartitionedDML(), "Automatically detect when a migration contains only Partitioned DML statements, and apply the statements in partition-level transactions via the PartitionedDML API. (optional. if not set, will use $WRENCH_DETECT_PARTITIONED_DML or default to false)")
 	rootCmd.PersistentFlags().Uint16Var(&partitionedDMLConcurrency, flagPartitionedDMLConcurrency, getPartitionedDMLConcurrency(), "Set the concurrency for Partitioned-DML statements. (optional. if not set, will use $WRENCH_PARTITIONED_DML_CONCURRENCY or default to 1)")
+	rootCmd.PersistentFlags().DurationVar(&progressInterval, flagProgressInterval, getProgressInterval(), "Interval between progress log lines of long-running operations when not writing to a terminal. (optional. if not set, will use $WRENCH_PROGRESS_INTERVAL or default to 30s)")
+	rootCmd.PersistentFlags().Uint16Var(&retryAttempts, flagRetryAttempts, getRetryAttempts(), "Maximum attempts of idempotent operations that fail with a transient error, e.g. version bookkeeping, lock operations and DDL submission. (optional. if not set, will use $WRENCH_RETRY_ATTEMPTS or default to 3)")
+	rootCmd.PersistentFlags().DurationVar(&retryBackoff, flagRetryBackoff, getRetryBackoff(), "Initial backoff between retries of transient errors, doubled after each attempt. (optional. if not set, will use $WRENCH_RETRY_BACKOFF or default to 1s)")
+	rootCmd.PersistentFlags().StringVar(&environment, flagEnvironment, os.Getenv("WRENCH_ENVIRONMENT"), "Environment being migrated. Migrations scoped to other environments with the @wrench.Environments directive are skipped. Required to apply or skip pending environment-scoped migrations, except on the emulator. (optional. if not set, will use $WRENCH_ENVIRONMENT)")
+	rootCmd.PersistentFlags().StringVar(&stream, flagStream, os.Getenv("WRENCH_STREAM"), "Migration stream, for teams that own separate migrations in the same database. Each stream has its own tracking tables and the directory 'streams/<stream>'. (optional. if not set, will use $WRENCH_STREAM or the default stream in 'migrations')")
+	rootCmd.PersistentFlags().StringArrayVar(&migrationsDirs, flagMigrationsDir, getMigrationsDirs(), "Directory of the default stream's migrations, relative to --directory. Repeat the flag to load several directories; new migrations and wrench.sum are written to the first. Subdirectories are loaded except for 'archive'. (optional. if not set, will use $WRENCH_MIGRATIONS_DIR separated by the OS path list separator, or default to 'migrations')")
+	rootCmd.PersistentFlags().StringVar(&envName, flagEnv, os.Getenv("WRENCH_ENV"), "Named environment in wrench.json, which sets the defaults of the project, instance, database and other flags. Flags and environment variables take precedence. (optional. if not set, will use $WRENCH_ENV)")
+	rootCmd.PersistentFlags().StringVar(&versionTable, flagVersionTable, cmp.Or(os.Getenv("WRENCH_VERSION_TABLE"), migrationTableName), "Name of the table that stores the migration version. The history tables are named after it. (optional. if not set, will use $WRENCH_VERSION_TABLE or default to SchemaMigrations)")
+	rootCmd.PersistentFlags().StringVar(&lockTable, flagLockTable, cmp.Or(os.Getenv("WRENCH_LOCK_TABLE"), migrationLockTable), "Name of the table that stores the migration lock. (optional. if not set, will use $WRENCH_LOCK_TABLE or default to SchemaMigrationsLock)")
+	rootCmd.PersistentFlags().StringVar(&outOfOrder, flagOutOfOrder, cmp.Or(os.Getenv("WRENCH_OUT_OF_ORDER"), "allow"), "How to handle pending migrations with a lower version than an applied migration: allow, warn or deny. (optional. if not set, will use $WRENCH_OUT_OF_ORDER or default to allow)")
 
 	rootCmd.Version = Version
 	if versioninfo.Version != "unknown" && versioninfo.Version != "(devel)" {
@@ -162,3 +198,31 @@ func getPartitionedDMLConcurrency() uint16 {
 	}
 	return uint16(i)
 }
+
+func getProgressInterval() time.Duration {
+	i, err := time.ParseDuration(os.Getenv("WRENCH_PROGRESS_INTERVAL"))
+	if err != nil {
+		return 30 * time.Second
+	}
+	return i
+}
+
+func getRetryAttempts() uint16 {
+	i, err := strconv.Atoi(os.Getenv("WRENCH_RETRY_ATTEMPTS"))
+	if err != nil {
+		return 3
+	}
+	return uint16(i)
+}
+
+func getRetryBackoff() time.Duration {
+	i, err := time.ParseDuration(os.Getenv("WRENCH_RETRY_BACKOFF"))
+	if err != nil {
+		return time.Second
+	}
+	return i
+}
+
+func getMigrationsDirs() []string {
+	return filepath.SplitList(os.Getenv("WRENCH_MIGRATIONS_DIR"))
+}
@@ -31,10 +31,18 @@ var schemaCmd = &cobra.Command{
 func init() {
 	// schema flags
 	schemaCmd.Flags().String(flagSpannerEmulatorImage, "roryq/spanner-emulator:latest", "Spanner emulator image to use. Override this to pin version or change registry.")
+	schemaCmd.Flags().Uint(flagAtVersion, 0, "Write the schema at the version of the migrations of --stream to --versions-output-dir, instead of the committed schema files")
+	schemaCmd.Flags().String(flagDiffVersions, "", "Write the schema at two versions of the migrations of --stream, e.g. 100..120, and the diff of each object to --versions-output-dir")
+	schemaCmd.Flags().String(flagVersionsOutputDir, "", "New or empty directory that --at-version and --diff-versions write to")
+	schemaCmd.MarkFlagsMutuallyExclusive(flagAtVersion, flagDiffVersions)
 
-	// copy migrate up flags
+	// copy migrate up flags, except for rehearsal as the schema command already runs on the emulator
 	if up := findCommand("up"); up != nil {
-		schemaCmd.Flags().AddFlagSet(up.LocalFlags())
+		up.LocalFlags().VisitAll(func(f *pflag.Flag) {
+			if f.Name != flagRehearse && f.Name != flagRehearseStaticData && schemaCmd.Flags().Lookup(f.Name) == nil {
+				schemaCmd.Flags().AddFlag(f)
+			}
+		})
 	}
 }
 
@@ -50,34 +58,23 @@ func findCommand(name string) *cobra.Command {
 func schema(c *cobra.Command, args []string) error {
 	defer gracefulSchemaTasks.Exit()
 
-	projectId := setIfUnset(c.Flag(flagNameProject), "project")
-	instanceId := setIfUnset(c.Flag(flagNameInstance), "instance")
-	databaseId := setIfUnset(c.Flag(flagNameDatabase), "database")
+	if c.Flags().Changed(flagAtVersion) || c.Flags().Changed(flagDiffVersions) {
+		return schemaAtVersions(c)
+	}
 
-	f := c.Flag(flagSpannerEmulatorImage)
-	_, err := runSpannerEmulator(f.Value.String(), projectId, instanceId, databaseId)
-	if err != nil {
+	if err := startEmulatorDatabase(c); err != nil {
 		return err
 	}
 
-	// create database
-	{
-		client, err := newSpannerClient(context.Background(), c)
-		if err != nil {
+	// run the migrations of every stream
+	names, err := allStreams(c)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := migrateUpStream(c, args, name); err != nil {
 			return err
 		}
-		if err := client.CreateDatabase(context.Background(), nil, nil); err != nil {
-			if status.Code(err) != codes.AlreadyExists {
-				_ = client.Close()
-				return err
-			}
-		}
-		_ = client.Close()
-	}
-
-	// run migrations
-	if err := migrateUp(c, args); err != nil {
-		return err
 	}
 
 	// load schema
@@ -93,6 +90,33 @@ func schema(c *cobra.Command, args []string) error {
 	return err
 }
 
+// startEmulatorDatabase runs the spanner emulator in docker and creates the database. The project, instance and
+// database default to the emulator's if not set. The container is removed by gracefulSchemaTasks.Exit.
+func startEmulatorDatabase(c *cobra.Command) error {
+	projectId := setIfUnset(c.Flag(flagNameProject), "project")
+	instanceId := setIfUnset(c.Flag(flagNameInstance), "instance")
+	databaseId := setIfUnset(c.Flag(flagNameDatabase), "database")
+
+	f := c.Flag(flagSpannerEmulatorImage)
+	_, err := runSpannerEmulator(f.Value.String(), projectId, instanceId, databaseId)
+	if err != nil {
+		return err
+	}
+
+	client, err := newSpannerClient(context.Background(), c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.CreateDatabase(context.Background(), nil, nil); err != nil {
+		if status.Code(err) != codes.AlreadyExists {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func setIfUnset(f *pflag.Flag, val string) string {
 	if f.Value.String() == "" {
 		_ = f.Value.Set(val)
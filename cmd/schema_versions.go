@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+
+	"github.com/roryq/wrench/pkg/core"
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+const schemaDiffFileName = "schema.diff"
+
+// schemaAtVersions runs the migrations of the stream against the emulator up to --at-version, or up to each version of
+// --diff-versions in turn, and writes the schema of each version to --versions-output-dir. The committed schema files
+// are not written. For --diff-versions the schema of each version is written to v<VERSION> and the
+// per-object diff to schema.diff.
+func schemaAtVersions(c *cobra.Command) error {
+	versions, err := schemaVersionsFlags(c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	outDir := c.Flag(flagVersionsOutputDir).Value.String()
+	if err := checkVersionsOutputDir(c, outDir); err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	if err := startEmulatorDatabase(c); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, err := newSpannerClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// each version continues from the previous one as the versions are in order
+	schemas := make([][]spanner.SchemaDDL, 0, len(versions))
+	for _, version := range versions {
+		if err := migrateUpStream(c, nil, stream, core.WithTargetVersion(version)); err != nil {
+			return err
+		}
+
+		dir := outDir
+		if len(versions) > 1 {
+			dir = filepath.Join(outDir, fmt.Sprintf("v%d", version))
+		}
+		ddls, err := writeSchemaAtVersion(ctx, c, client, dir)
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+		schemas = append(schemas, ddls)
+		fmt.Printf("wrote the schema at version %d to %s\n", version, dir)
+	}
+
+	if len(versions) == 1 {
+		return nil
+	}
+
+	diff, changes, err := diffSchemaDDLs(schemas[0], schemas[1], fmt.Sprintf("v%d", versions[0]), fmt.Sprintf("v%d", versions[1]))
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Printf("no changes between versions %d and %d\n", versions[0], versions[1])
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	diffPath := filepath.Join(outDir, schemaDiffFileName)
+	if err := os.WriteFile(diffPath, []byte(diff), 0o644); err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+	fmt.Printf("wrote the diff between versions %d and %d to %s\n", versions[0], versions[1], diffPath)
+
+	return nil
+}
+
+// checkVersionsOutputDir returns an error unless the directory is set, is not the directory of the committed schema
+// files, and is new or empty, so that no committed files are overwritten.
+func checkVersionsOutputDir(c *cobra.Command, dir string) error {
+	if dir == "" {
+		return fmt.Errorf("--%s must be set with --%s or --%s", flagVersionsOutputDir, flagAtVersion, flagDiffVersions)
+	}
+
+	for _, committed := range []string{c.Flag(flagNameDirectory).Value.String(), schemaDirPath(c)} {
+		if filepath.Clean(dir) == filepath.Clean(committed) {
+			return fmt.Errorf("--%s must not be the directory of the committed schema files %s", flagVersionsOutputDir, committed)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("--%s %s must be a new or empty directory", flagVersionsOutputDir, dir)
+	}
+
+	return nil
+}
+
+// schemaVersionsFlags returns the version of --at-version, or the two versions of --diff-versions, or nil if neither
+// is set.
+func schemaVersionsFlags(c *cobra.Command) ([]uint, error) {
+	if c.Flags().Changed(flagDiffVersions) {
+		from, to, err := parseVersionRange(c.Flag(flagDiffVersions).Value.String())
+		if err != nil {
+			return nil, err
+		}
+		return []uint{from, to}, nil
+	}
+
+	if c.Flags().Changed(flagAtVersion) {
+		version, err := c.Flags().GetUint(flagAtVersion)
+		if err != nil {
+			return nil, err
+		}
+		if version == 0 {
+			return nil, fmt.Errorf("--%s must be a version greater than 0", flagAtVersion)
+		}
+		return []uint{version}, nil
+	}
+
+	return nil, nil
+}
+
+// parseVersionRange parses a range of two versions, e.g. 100..120.
+func parseVersionRange(s string) (uint, uint, error) {
+	invalid := fmt.Errorf("invalid --%s %q, must be FROM..TO, e.g. 100..120", flagDiffVersions, s)
+
+	fromStr, toStr, ok := strings.Cut(s, "..")
+	if !ok {
+		return 0, 0, invalid
+	}
+	from, err := strconv.ParseUint(fromStr, 10, 0)
+	if err != nil || from == 0 {
+		return 0, 0, invalid
+	}
+	to, err := strconv.ParseUint(toStr, 10, 0)
+	if err != nil {
+		return 0, 0, invalid
+	}
+	if from >= to {
+		return 0, 0, fmt.Errorf("invalid --%s %q, FROM must be lower than TO", flagDiffVersions, s)
+	}
+
+	return uint(from), uint(to), nil
+}
+
+// writeSchemaAtVersion writes the schema file and the discrete files of each object of the database to the directory,
+// and returns the objects.
+func writeSchemaAtVersion(ctx context.Context, c *cobra.Command, client *spanner.Client, dir string) ([]spanner.SchemaDDL, error) {
+	ddl, err := client.LoadDDL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ddls, err := client.LoadDDLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mkdir(dir); err != nil {
+		return nil, err
+	}
+	schemaFileName := cmp.Or(c.Flag(flagNameSchemaFile).Value.String(), defaultSchemaFileName)
+	if err := os.WriteFile(filepath.Join(dir, filepath.Base(schemaFileName)), ddl, 0o644); err != nil {
+		return nil, err
+	}
+
+	for _, ddl := range ddls {
+		if err := writeDDL(ddl, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return ddls, nil
+}
+
+// diffSchemaDDLs returns the unified diff of each object that was added, removed or changed between the two schemas,
+// and a line per changed object, e.g. "changed table/singers.sql". Objects are identified by the path of their
+// discrete file.
+func diffSchemaDDLs(from, to []spanner.SchemaDDL, fromLabel, toLabel string) (string, []string, error) {
+	objectStatements := func(ddls []spanner.SchemaDDL) map[string]string {
+		objects := make(map[string]string, len(ddls))
+		for _, ddl := range ddls {
+			objects[path.Join(ddl.ObjectType, ddl.Filename)] = ddl.Statement
+		}
+		return objects
+	}
+	fromObjects, toObjects := objectStatements(from), objectStatements(to)
+
+	names := slices.Sorted(maps.Keys(fromObjects))
+	for name := range toObjects {
+		if _, ok := fromObjects[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var diff strings.Builder
+	var changes []string
+	for _, name := range names {
+		fromStatement, inFrom := fromObjects[name]
+		toStatement, inTo := toObjects[name]
+		if inFrom && inTo && fromStatement == toStatement {
+			continue
+		}
+
+		ud := difflib.UnifiedDiff{
+			A:        splitLines(fromStatement),
+			B:        splitLines(toStatement),
+			FromFile: path.Join(fromLabel, name),
+			ToFile:   path.Join(toLabel, name),
+			Context:  3,
+		}
+		switch {
+		case !inFrom:
+			ud.FromFile = "/dev/null"
+			changes = append(changes, "added "+name)
+		case !inTo:
+			ud.ToFile = "/dev/null"
+			changes = append(changes, "removed "+name)
+		default:
+			changes = append(changes, "changed "+name)
+		}
+
+		if err := difflib.WriteUnifiedDiff(&diff, ud); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return diff.String(), changes, nil
+}
+
+// splitLines splits the statement into lines that each end with a newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	return lines
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+func Test_parseVersionRange(t *testing.T) {
+	from, to, err := parseVersionRange("100..120")
+	require.NoError(t, err)
+	assert.Equal(t, uint(100), from)
+	assert.Equal(t, uint(120), to)
+
+	for _, s := range []string{"100", "100..", "..120", "0..120", "a..b", "100...120"} {
+		_, _, err := parseVersionRange(s)
+		assert.ErrorContains(t, err, "must be FROM..TO", s)
+	}
+
+	_, _, err = parseVersionRange("120..100")
+	assert.ErrorContains(t, err, "FROM must be lower than TO")
+}
+
+func Test_diffSchemaDDLs(t *testing.T) {
+	from := []spanner.SchemaDDL{
+		{ObjectType: spanner.ObjectTypeTable, Filename: "singers.sql", Statement: "CREATE TABLE Singers (\n  SingerID STRING(36) NOT NULL,\n) PRIMARY KEY(SingerID);\n"},
+		{ObjectType: spanner.ObjectTypeIndex, Filename: "ix_singers_firstname.sql", Statement: "CREATE INDEX IX_Singers_FirstName ON Singers(FirstName);\n"},
+		{ObjectType: spanner.ObjectTypeView, Filename: "singernames.sql", Statement: "CREATE VIEW SingerNames AS SELECT FirstName FROM Singers;\n"},
+	}
+	to := []spanner.SchemaDDL{
+		{ObjectType: spanner.ObjectTypeTable, Filename: "singers.sql", Statement: "CREATE TABLE Singers (\n  SingerID STRING(36) NOT NULL,\n  FirstName STRING(1024),\n) PRIMARY KEY(SingerID);\n"},
+		{ObjectType: spanner.ObjectTypeTable, Filename: "albums.sql", Statement: "CREATE TABLE Albums (\n  AlbumID STRING(36) NOT NULL,\n) PRIMARY KEY(AlbumID);\n"},
+		{ObjectType: spanner.ObjectTypeView, Filename: "singernames.sql", Statement: "CREATE VIEW SingerNames AS SELECT FirstName FROM Singers;\n"},
+	}
+
+	diff, changes, err := diffSchemaDDLs(from, to, "v1", "v2")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"removed index/ix_singers_firstname.sql",
+		"added table/albums.sql",
+		"changed table/singers.sql",
+	}, changes)
+	assert.Equal(t, `--- v1/index/ix_singers_firstname.sql
++++ /dev/null
+@@ -1 +0,0 @@
+-CREATE INDEX IX_Singers_FirstName ON Singers(FirstName);
+--- /dev/null
++++ v2/table/albums.sql
+@@ -0,0 +1,3 @@
++CREATE TABLE Albums (
++  AlbumID STRING(36) NOT NULL,
++) PRIMARY KEY(AlbumID);
+--- v1/table/singers.sql
++++ v2/table/singers.sql
+@@ -1,3 +1,4 @@
+ CREATE TABLE Singers (
+   SingerID STRING(36) NOT NULL,
++  FirstName STRING(1024),
+ ) PRIMARY KEY(SingerID);
+`, diff)
+
+	diff, changes, err = diffSchemaDDLs(to, to, "v2", "v2")
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+	assert.Empty(t, changes)
+}
+
+func Test_checkVersionsOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "schema", "table"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0o755))
+
+	c := &cobra.Command{}
+	c.Flags().String(flagNameDirectory, dir, "")
+	c.Flags().String(flagNameOutputDir, filepath.Join(dir, "schema"), "")
+
+	assert.ErrorContains(t, checkVersionsOutputDir(c, ""), "must be set")
+	assert.ErrorContains(t, checkVersionsOutputDir(c, dir), "must not be the directory of the committed schema files")
+	assert.ErrorContains(t, checkVersionsOutputDir(c, filepath.Join(dir, "schema")+"/"), "must not be the directory of the committed schema files")
+	assert.ErrorContains(t, checkVersionsOutputDir(c, filepath.Join(dir, "migrations", "..")), "must not be the directory of the committed schema files")
+	assert.NoError(t, checkVersionsOutputDir(c, filepath.Join(dir, "empty")))
+	assert.NoError(t, checkVersionsOutputDir(c, filepath.Join(dir, "new")))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty", "schema.sql"), nil, 0o644))
+	assert.ErrorContains(t, checkVersionsOutputDir(c, filepath.Join(dir, "empty")), "must be a new or empty directory")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roryq/wrench/pkg/core"
+)
+
+var seedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Apply seed data files that have changed since they were last applied",
+	Long: `Apply the seed data files in the migrations directory, e.g. S__users.sql. Like repeatable migrations, a seed is
+applied whenever its checksum changes, so seeds must be idempotent (e.g. INSERT OR UPDATE). Seeds are tracked in the
+SchemaMigrationsSeedHistory table, not the migration history. Use the @wrench.Environments directive to apply a seed
+only to the named environments.`,
+	RunE: seed,
+}
+
+func init() {
+	seedCmd.Flags().Bool(flagPlaceholderReplacement, true, "Enable placeholder replacement for ${PROJECT_ID}, ${INSTANCE_ID}, ${DATABASE_ID} and user-defined placeholders")
+	seedCmd.Flags().StringArray(flagPlaceholder, nil, "Placeholder value as KEY=VALUE, can be repeated. Takes precedence over $WRENCH_PLACEHOLDER_KEY and the placeholders file")
+	seedCmd.Flags().String(flagPlaceholdersFile, "", "JSON file of placeholder values (optional. if not set, will use 'placeholders.<environment>.json' or 'placeholders.json' if it exists)")
+}
+
+func seed(c *cobra.Command, _ []string) error {
+	ctx := context.Background()
+
+	client, err := newSpannerClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	placeholdersEnabled, err := c.Flags().GetBool(flagPlaceholderReplacement)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	placeholders, err := userPlaceholders(c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	err = core.Seed(ctx, client, streamMigrationsDir(c, stream),
+		core.WithLockIdentifier(lockIdentifier),
+		core.WithVersionTable(streamVersionTable(stream)),
+		core.WithLockTable(lockTable),
+		core.WithPartitionedDMLConcurrency(partitionedDMLConcurrency),
+		core.WithDetectPartitionedDML(detectPartitionedDML),
+		core.WithPrintRowsAffected(verbose),
+		core.WithDefaultPlaceholders(
+			placeholdersEnabled,
+			c.Flag(flagNameProject).Value.String(),
+			c.Flag(flagNameInstance).Value.String(),
+			c.Flag(flagNameDatabase).Value.String(),
+		),
+		core.WithPlaceholders(placeholders),
+		core.WithEnvironment(environment),
+	)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roryq/wrench/internal/fs"
+	"github.com/roryq/wrench/pkg/core"
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+var migrateSquashCmd = &cobra.Command{
+	Use:   "squash",
+	Short: "Collapse the migrations up to a version into a single baseline migration. (Requires docker)",
+	Long: `Collapse the migrations up to and including the version set by --up-to into a single baseline migration.
+
+The migrations are replayed against a dockerised spanner emulator, then the resulting schema and static data are written
+to N_baseline.sql in the migrations directory, and the original files are moved to the archive directory. Migrations
+that use placeholders or templates cannot be squashed, as the values of the emulator would be written to the baseline.
+
+The squashed versions are listed by the @wrench.Squashed directive of the baseline. Databases that have applied every
+squashed version record the baseline as applied without executing it. Databases that have applied only some of them
+fail until the missing versions are applied from the archive, as recording the baseline would skip the schema of the
+missing versions for good. New databases apply the schema of the baseline in a single operation, followed by its static
+data.`,
+	RunE: migrateSquash,
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateSquashCmd)
+
+	migrateSquashCmd.Flags().Uint(flagUpTo, 0, "Squash the migrations up to and including this version")
+	migrateSquashCmd.Flags().String(flagArchiveDir, "", "Directory the squashed migrations are moved to (optional. if not set, will use 'archive' in the migrations directory)")
+	migrateSquashCmd.Flags().String(flagSpannerEmulatorImage, "roryq/spanner-emulator:latest", "Spanner emulator image to use. Override this to pin version or change registry.")
+	migrateSquashCmd.Flags().Bool(flagPlaceholderReplacement, true, "Enable placeholder replacement for ${PROJECT_ID}, ${INSTANCE_ID}, ${DATABASE_ID} and user-defined placeholders")
+	migrateSquashCmd.Flags().StringArray(flagPlaceholder, nil, "Placeholder value as KEY=VALUE, can be repeated. Takes precedence over $WRENCH_PLACEHOLDER_KEY and the placeholders file")
+	migrateSquashCmd.Flags().String(flagPlaceholdersFile, "", "JSON file of placeholder values (optional. if not set, will use 'placeholders.<environment>.json' or 'placeholders.json' if it exists)")
+	migrateSquashCmd.Flags().String(flagProtoDescriptorFile, "", "Proto descriptor file to be used with migrations")
+	_ = migrateSquashCmd.MarkFlagRequired(flagUpTo)
+}
+
+func migrateSquash(c *cobra.Command, _ []string) error {
+	defer gracefulSchemaTasks.Exit()
+	ctx := context.Background()
+
+	upTo, err := c.Flags().GetUint(flagUpTo)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+	if upTo == 0 {
+		return &Error{
+			cmd: c,
+			err: errors.New("--up-to must be a version greater than 0"),
+		}
+	}
+
+	migrationsDir := streamMigrationsDir(c, stream)
+	archiveDir := c.Flag(flagArchiveDir).Value.String()
+	if archiveDir == "" {
+		archiveDir = filepath.Join(spanner.MigrationsDirs(migrationsDir)[0], spanner.ArchiveDirName)
+	}
+
+	placeholdersEnabled, err := c.Flags().GetBool(flagPlaceholderReplacement)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	placeholders, err := userPlaceholders(c)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	var protoDescriptor []byte
+	if protoDescriptorFile := protoDescriptorFilePath(c); protoDescriptorFile != "" {
+		protoDescriptor, err = fs.ReadFile(ctx, protoDescriptorFile)
+		if err != nil {
+			return &Error{
+				cmd: c,
+				err: err,
+			}
+		}
+	}
+
+	config, err := readStaticDataTablesFile(staticDataTablesFilePath(c))
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	if err := startEmulatorDatabase(c); err != nil {
+		return err
+	}
+
+	client, err := newSpannerClient(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	baseline, err := core.Squash(ctx, client, migrationsDir, upTo, archiveDir,
+		core.WithLockIdentifier(lockIdentifier),
+		core.WithVersionTable(streamVersionTable(stream)),
+		core.WithLockTable(lockTable),
+		core.WithPartitionedDMLConcurrency(partitionedDMLConcurrency),
+		core.WithDetectPartitionedDML(detectPartitionedDML),
+		core.WithDefaultPlaceholders(
+			placeholdersEnabled,
+			c.Flag(flagNameProject).Value.String(),
+			c.Flag(flagNameInstance).Value.String(),
+			c.Flag(flagNameDatabase).Value.String(),
+		),
+		core.WithPlaceholders(placeholders),
+		core.WithProtoDescriptors(protoDescriptor),
+		core.WithStaticDataTables(config.StaticDataTables, config.CustomOrderBy),
+	)
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	fmt.Printf("wrote %s, squashed migrations moved to %s\n", baseline, archiveDir)
+	return nil
+}
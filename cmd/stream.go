@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const streamsDirName = "streams"
+
+// streamNameRegex limits stream names to characters that are valid in a table name.
+var streamNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// streamVersionTable returns the version table of the migration stream, e.g. billing_SchemaMigrations. The history and
+// repeatable history tables are named after the version table. The lock table is shared by all streams so that they
+// never migrate at the same time.
+func streamVersionTable(name string) string {
+	if name == "" {
+		return versionTable
+	}
+	return name + "_" + versionTable
+}
+
+// streamMigrationsDir returns the migrations directory of the stream, <directory>/migrations for the default stream
+// and <directory>/streams/<name> otherwise. The default stream loads the directories set by --migrations-dir instead,
+// joined by os.PathListSeparator.
+func streamMigrationsDir(c *cobra.Command, name string) string {
+	if name == "" {
+		if len(migrationsDirs) == 0 {
+			return filepath.Join(c.Flag(flagNameDirectory).Value.String(), migrationsDirName)
+		}
+
+		dirs := make([]string, 0, len(migrationsDirs))
+		for _, d := range migrationsDirs {
+			if !filepath.IsAbs(d) {
+				d = filepath.Join(c.Flag(flagNameDirectory).Value.String(), d)
+			}
+			dirs = append(dirs, d)
+		}
+		return strings.Join(dirs, string(os.PathListSeparator))
+	}
+	return filepath.Join(c.Flag(flagNameDirectory).Value.String(), streamsDirName, name)
+}
+
+// streamLabel returns the name of the stream for output.
+func streamLabel(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+func validateStream(name string) error {
+	if name != "" && !streamNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid stream name %q, must start with a letter and contain only letters, digits and underscores", name)
+	}
+	return nil
+}
+
+// allStreams returns the stream set by --stream. Otherwise it returns the default stream followed by each directory in
+// <directory>/streams.
+func allStreams(c *cobra.Command) ([]string, error) {
+	if stream != "" {
+		return []string{stream}, nil
+	}
+
+	names := []string{""}
+	entries, err := os.ReadDir(filepath.Join(c.Flag(flagNameDirectory).Value.String(), streamsDirName))
+	if errors.Is(err, os.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if err := validateStream(e.Name()); err != nil {
+			return nil, err
+		}
+		names = append(names, e.Name())
+	}
+
+	return names, nil
+}
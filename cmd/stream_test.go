@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_allStreams(t *testing.T) {
+	dir := t.TempDir()
+	c := &cobra.Command{}
+	c.Flags().String(flagNameDirectory, dir, "")
+
+	names, err := allStreams(c)
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, names)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, streamsDirName, "billing"), os.ModePerm))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, streamsDirName, "search"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, streamsDirName, "README.md"), nil, 0o644))
+
+	names, err = allStreams(c)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "billing", "search"}, names)
+
+	assert.Equal(t, "SchemaMigrations", streamVersionTable(""))
+	assert.Equal(t, "billing_SchemaMigrations", streamVersionTable("billing"))
+	assert.Equal(t, filepath.Join(dir, "migrations"), streamMigrationsDir(c, ""))
+	assert.Equal(t, filepath.Join(dir, "streams", "billing"), streamMigrationsDir(c, "billing"))
+}
+
+func Test_validateStream(t *testing.T) {
+	assert.NoError(t, validateStream(""))
+	assert.NoError(t, validateStream("billing_v2"))
+	assert.Error(t, validateStream("2billing"))
+	assert.Error(t, validateStream("billing-team"))
+	assert.Error(t, validateStream("billing; DROP TABLE Singers"))
+}
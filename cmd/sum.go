@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roryq/wrench/pkg/core"
+)
+
+var migrateSumCmd = &cobra.Command{
+	Use:   "sum",
+	Short: "Write the wrench.sum manifest of the migration files",
+	Long: `Write the wrench.sum manifest of the migration files in the migrations directory. The manifest lists each migration
+file in version order with the hash of its contents, including its assert file and emulator replacement file. Check it
+into source control so that two branches adding the same version show up as a merge conflict in the manifest. Once the
+manifest exists, migrate create keeps it up to date.`,
+	RunE: migrateSum,
+}
+
+var migrateValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that the migration files match the wrench.sum manifest",
+	Long: `Check that the migration files match the wrench.sum manifest. Fails if a migration file was added, edited or deleted
+without running migrate sum, or if a version is used by more than one migration file.`,
+	RunE: migrateValidate,
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateSumCmd, migrateValidateCmd)
+}
+
+func migrateSum(c *cobra.Command, _ []string) error {
+	path, err := core.WriteSum(streamMigrationsDir(c, stream))
+	if err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	fmt.Printf("wrote %s\n", path)
+	return nil
+}
+
+func migrateValidate(c *cobra.Command, _ []string) error {
+	if err := core.ValidateSum(streamMigrationsDir(c, stream)); err != nil {
+		return &Error{
+			cmd: c,
+			err: err,
+		}
+	}
+
+	fmt.Println("migrations match wrench.sum")
+	return nil
+}
@@ -1,6 +1,7 @@
 package graceful
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"sync"
@@ -42,3 +43,31 @@ func (s *OnShutdown) Exit() {
 	}
 	s.todo = nil
 }
+
+// StopThenCancel handles shutdown signals in two stages. The first signal calls stop so that the current work can
+// finish, a second signal cancels the returned context. Call the returned CancelFunc to stop handling signals.
+func StopThenCancel(ctx context.Context, stop func()) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigc:
+			stop()
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case <-sigc:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigc)
+		cancel()
+	}
+}
@@ -0,0 +1,28 @@
+package graceful
+
+import (
+	"context"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopThenCancel(t *testing.T) {
+	var stopped atomic.Bool
+	ctx, cancel := StopThenCancel(context.Background(), func() { stopped.Store(true) })
+	defer cancel()
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+	assert.Eventually(t, stopped.Load, time.Second, 10*time.Millisecond)
+	assert.NoError(t, ctx.Err(), "the first signal should not cancel the context")
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the second signal should cancel the context")
+	}
+}
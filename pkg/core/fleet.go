@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// FleetStatus is the outcome of migrating one database of a fleet.
+type FleetStatus string
+
+const (
+	FleetStatusMigrated  FleetStatus = "migrated"
+	FleetStatusUnchanged FleetStatus = "unchanged"
+	FleetStatusFailed    FleetStatus = "failed"
+	FleetStatusSkipped   FleetStatus = "skipped"
+)
+
+// FleetResult is the result of migrating one database of a fleet.
+type FleetResult struct {
+	Database      string      `json:"database"`
+	Status        FleetStatus `json:"status"`
+	VersionBefore uint        `json:"versionBefore"`
+	VersionAfter  uint        `json:"versionAfter"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// FleetMigrate runs MigrateUp against each database of a fleet, with up to WithFleetConcurrency databases at the same
+// time. Each database takes its own migration lock, and the default placeholders are set to the project, instance and
+// database of each database if placeholders are enabled with WithPlaceholdersEnabled. After a database fails the
+// databases that have not started are skipped, unless WithContinueOnError is set. Returns the result of each database
+// in the order of configs, and an error if any database failed.
+func FleetMigrate(ctx context.Context, configs []*spanner.Config, migrationsDir string, opts ...MigrateOpt) ([]FleetResult, error) {
+	options := defaultMigrateOptions()
+	for _, optFn := range opts {
+		if err := optFn(options); err != nil {
+			return nil, err
+		}
+	}
+
+	// fail before connecting to any database if the migrations cannot be loaded
+	if _, err := spanner.LoadMigrations(migrationsDir, options.SkipVersions, options.DetectPartitionedDML, spanner.PlaceholderOptions{}); err != nil {
+		return nil, err
+	}
+
+	results := make([]FleetResult, len(configs))
+	var failed atomic.Bool
+	sem := make(chan struct{}, options.FleetConcurrency)
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		results[i] = FleetResult{Database: config.URL(), Status: FleetStatusSkipped}
+	}
+	for i, config := range configs {
+		select {
+		case sem <- struct{}{}:
+		case <-options.FleetStop:
+		case <-ctx.Done():
+		}
+		if isStopped(ctx, options.FleetStop) || (failed.Load() && !options.ContinueOnError) {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			dbOpts := append(slices.Clone(opts), WithDefaultPlaceholders(options.PlaceholdersEnabled, config.Project, config.Instance, config.Database))
+			results[i] = migrateFleetDatabase(ctx, config, migrationsDir, options.VersionTableName, dbOpts)
+			if results[i].Status == FleetStatusFailed {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Status == FleetStatusFailed {
+			errs = append(errs, fmt.Errorf("%s: %s", r.Database, r.Error))
+		}
+	}
+	if len(errs) > 0 {
+		return results, fmt.Errorf("%d of %d databases failed to migrate:\n%w", len(errs), len(configs), errors.Join(errs...))
+	}
+
+	return results, nil
+}
+
+// migrateFleetDatabase migrates one database of a fleet and records its versions before and after.
+func migrateFleetDatabase(ctx context.Context, config *spanner.Config, migrationsDir, versionTableName string, opts []MigrateOpt) FleetResult {
+	result := FleetResult{Database: config.URL()}
+
+	client, err := spanner.NewClient(ctx, config)
+	if err != nil {
+		result.Status = FleetStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	defer client.Close()
+
+	result.VersionBefore = fleetVersion(ctx, client, versionTableName)
+	err = MigrateUp(ctx, client, migrationsDir, opts...)
+	result.VersionAfter = fleetVersion(context.WithoutCancel(ctx), client, versionTableName)
+
+	switch {
+	case err != nil:
+		result.Status = FleetStatusFailed
+		result.Error = err.Error()
+	case result.VersionAfter != result.VersionBefore:
+		result.Status = FleetStatusMigrated
+	default:
+		result.Status = FleetStatusUnchanged
+	}
+
+	return result
+}
+
+// fleetVersion returns the current version of the database, or 0 if no migration has been applied.
+func fleetVersion(ctx context.Context, client *spanner.Client, versionTableName string) uint {
+	version, _, err := client.GetSchemaMigrationVersion(ctx, versionTableName)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+func isStopped(ctx context.Context, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// PrintFleetResults prints a summary table of the fleet results.
+func PrintFleetResults(w io.Writer, results []FleetResult) {
+	writer := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	_, _ = fmt.Fprintln(writer, "Database\tBefore\tAfter\tStatus\tError")
+	for _, r := range results {
+		// the full error is in the output of the database and the JSON report
+		firstLine, _, _ := strings.Cut(r.Error, "\n")
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%d\t%s\t%s\n", r.Database, r.VersionBefore, r.VersionAfter, r.Status, firstLine)
+	}
+	_ = writer.Flush()
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+const (
+	envSpannerProjectID  = "SPANNER_PROJECT_ID"
+	envSpannerInstanceID = "SPANNER_INSTANCE_ID"
+)
+
+func TestFleetMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	migrationsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, "000001_create_singers.sql"),
+		[]byte("CREATE TABLE Singers (SingerID STRING(36) NOT NULL) PRIMARY KEY (SingerID);\n"), 0o644))
+
+	statuses := func(results []FleetResult) []FleetStatus {
+		var got []FleetStatus
+		for _, r := range results {
+			got = append(got, r.Status)
+		}
+		return got
+	}
+
+	t.Run("migrates each database", func(t *testing.T) {
+		configs := testDatabases(t, ctx, 2)
+
+		results, err := FleetMigrate(ctx, configs, migrationsDir, WithFleetConcurrency(2))
+		require.NoError(t, err)
+		assert.Equal(t, []FleetStatus{FleetStatusMigrated, FleetStatusMigrated}, statuses(results))
+		assert.Equal(t, uint(1), results[0].VersionAfter)
+
+		results, err = FleetMigrate(ctx, configs, migrationsDir, WithFleetConcurrency(2))
+		require.NoError(t, err)
+		assert.Equal(t, []FleetStatus{FleetStatusUnchanged, FleetStatusUnchanged}, statuses(results))
+	})
+
+	t.Run("skips the databases after a failure", func(t *testing.T) {
+		// the first database does not exist so fails to migrate
+		configs := append([]*spanner.Config{testDatabaseConfig(t)}, testDatabases(t, ctx, 1)...)
+
+		results, err := FleetMigrate(ctx, configs, migrationsDir)
+		assert.ErrorContains(t, err, "1 of 2 databases failed to migrate")
+		assert.Equal(t, []FleetStatus{FleetStatusFailed, FleetStatusSkipped}, statuses(results))
+		assert.NotEmpty(t, results[0].Error)
+	})
+
+	t.Run("continues after a failure", func(t *testing.T) {
+		// the first database does not exist so fails to migrate
+		configs := append([]*spanner.Config{testDatabaseConfig(t)}, testDatabases(t, ctx, 1)...)
+
+		results, err := FleetMigrate(ctx, configs, migrationsDir, WithContinueOnError(true))
+		assert.ErrorContains(t, err, "1 of 2 databases failed to migrate")
+		assert.Equal(t, []FleetStatus{FleetStatusFailed, FleetStatusMigrated}, statuses(results))
+	})
+
+	t.Run("stops before starting databases", func(t *testing.T) {
+		configs := testDatabases(t, ctx, 2)
+
+		stop := make(chan struct{})
+		close(stop)
+		results, err := FleetMigrate(ctx, configs, migrationsDir, WithFleetStop(stop))
+		require.NoError(t, err)
+		assert.Equal(t, []FleetStatus{FleetStatusSkipped, FleetStatusSkipped}, statuses(results))
+	})
+}
+
+// testDatabases creates n empty databases that are dropped when the test finishes.
+func testDatabases(t *testing.T, ctx context.Context, n int) []*spanner.Config {
+	t.Helper()
+
+	var configs []*spanner.Config
+	for range n {
+		config := testDatabaseConfig(t)
+
+		client, err := spanner.NewClient(ctx, config)
+		require.NoError(t, err)
+		require.NoError(t, client.CreateDatabase(ctx, nil, nil))
+		t.Cleanup(func() {
+			defer client.Close()
+			if err := client.DropDatabase(ctx); err != nil {
+				t.Errorf("failed to delete database: %v", err)
+			}
+		})
+
+		configs = append(configs, config)
+	}
+
+	return configs
+}
+
+// testDatabaseConfig returns the config of a new database on the test instance, which is not created.
+func testDatabaseConfig(t *testing.T) *spanner.Config {
+	t.Helper()
+
+	project := os.Getenv(envSpannerProjectID)
+	if project == "" {
+		t.Fatalf("must set %s", envSpannerProjectID)
+	}
+
+	instance := os.Getenv(envSpannerInstanceID)
+	if instance == "" {
+		t.Fatalf("must set %s", envSpannerInstanceID)
+	}
+
+	return &spanner.Config{
+		Project:  project,
+		Instance: instance,
+		Database: fmt.Sprintf("wrench-test-%s", uuid.New().String()[:8]),
+	}
+}
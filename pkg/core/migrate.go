@@ -9,13 +9,18 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/roryq/wrench/pkg/spanner"
 )
 
-// CreateMigrationFile creates a new migration file in the given directory. The name should be alphanumeric with underscores
-// or dashes only.
+// CreateMigrationFile creates a new migration file in the given directory, and updates the wrench.sum manifest if the
+// directory has one. The name should be alphanumeric with underscores or dashes only. The file is empty unless a
+// template is set with WithTemplate. If dir is a list of directories joined by os.PathListSeparator, the version
+// follows the migrations in all of them and the file is created in the first, or in its subdirectory set with
+// WithSubdirectory.
 // The sequence options configure how the migration sequence is created.
 func CreateMigrationFile(dir string, name string, opts ...MigrationSequenceOpt) (string, error) {
 	options := defaultSequenceOptions()
@@ -29,6 +34,18 @@ func CreateMigrationFile(dir string, name string, opts ...MigrationSequenceOpt)
 		return "", errors.New("Invalid migration file name.")
 	}
 
+	targetDir := filepath.Join(spanner.MigrationsDirs(dir)[0], options.Subdirectory)
+	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if options.Repeatable {
+		if name == "" {
+			return "", errors.New("A repeatable migration requires a name.")
+		}
+		return writeMigrationFile(dir, filepath.Join(targetDir, fmt.Sprintf("R__%s.sql", name)), options.Template)
+	}
+
 	ms, err := spanner.LoadMigrations(dir, nil, false, spanner.PlaceholderOptions{ReplacementEnabled: false})
 	if err != nil {
 		return "", err
@@ -42,24 +59,43 @@ func CreateMigrationFile(dir string, name string, opts ...MigrationSequenceOpt)
 	}
 
 	var v uint = 1
-	if len(versionedMigrations) > 0 {
-		v = roundNext(versionedMigrations[len(versionedMigrations)-1].Version, options.Interval)
+	if len(versionedMigrations) > 0 || options.Strategy == SequenceTimestamp {
+		var latest uint
+		if len(versionedMigrations) > 0 {
+			latest = versionedMigrations[len(versionedMigrations)-1].Version
+		}
+		v = nextVersion(latest, options)
 	}
 
 	vStr := fmt.Sprintf("%0*d", options.ZeroPrefixLength, v)
 
 	var filename string
 	if name == "" {
-		filename = filepath.Join(dir, fmt.Sprintf("%s.sql", vStr))
+		filename = filepath.Join(targetDir, fmt.Sprintf("%s.sql", vStr))
 	} else {
-		filename = filepath.Join(dir, fmt.Sprintf("%s_%s.sql", vStr, name))
+		filename = filepath.Join(targetDir, fmt.Sprintf("%s_%s.sql", vStr, name))
 	}
 
-	fp, err := os.Create(filename)
+	return writeMigrationFile(dir, filename, options.Template)
+}
+
+// writeMigrationFile creates the migration file with the template contents, and updates the wrench.sum manifest.
+func writeMigrationFile(dir, filename, template string) (string, error) {
+	fp, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
 	if err != nil {
 		return "", err
 	}
-	_ = fp.Close()
+	if _, err := fp.WriteString(template); err != nil {
+		_ = fp.Close()
+		return "", err
+	}
+	if err := fp.Close(); err != nil {
+		return "", err
+	}
+
+	if err := updateSum(dir); err != nil {
+		return "", err
+	}
 
 	return filename, nil
 }
@@ -68,7 +104,23 @@ func roundNext(n, next uint) uint {
 	return uint(math.Round(float64(n)/float64(next)))*next + next
 }
 
+// timestampVersionFormat is the format of versions generated by the timestamp strategy.
+const timestampVersionFormat = "20060102150405"
+
+// nextVersion returns the version after latest for the sequence strategy. A timestamp version is always greater than
+// latest, even if the clock is behind the latest migration.
+func nextVersion(latest uint, options migrationSequenceOptions) uint {
+	if options.Strategy != SequenceTimestamp {
+		return roundNext(latest, options.Interval)
+	}
+
+	v, _ := strconv.ParseUint(options.Now().UTC().Format(timestampVersionFormat), 10, 64)
+	return max(uint(v), latest+1)
+}
+
 // MigrateUp runs all migrations that haven't been run yet based on the contents of the history table.
+// Call client.StopAfterCurrentMigration to stop before the next migration, or cancel ctx to also cancel the migration
+// in progress. Either way the lock is released and a summary of the resulting state is printed.
 func MigrateUp(ctx context.Context, client *spanner.Client, migrationsDir string, opts ...MigrateOpt) error {
 	options := defaultMigrateOptions()
 	for _, optFn := range opts {
@@ -77,6 +129,15 @@ func MigrateUp(ctx context.Context, client *spanner.Client, migrationsDir string
 		}
 	}
 
+	err := migrateUp(ctx, client, migrationsDir, options)
+	if client.Stopping() || ctx.Err() != nil {
+		printInterruptedSummary(context.WithoutCancel(ctx), client, options.VersionTableName, ctx.Err() != nil)
+	}
+
+	return err
+}
+
+func migrateUp(ctx context.Context, client *spanner.Client, migrationsDir string, options *migrateOptions) error {
 	lock, err := client.GetMigrationLock(ctx, options.LockTableName, options.LockIdentifier)
 	defer lock.Release()
 	if err != nil {
@@ -90,20 +151,31 @@ func MigrateUp(ctx context.Context, client *spanner.Client, migrationsDir string
 	if err != nil {
 		return err
 	}
+	migrations.SkipEnvironments(options.Environment)
 
 	var versionedMigrations, repeatableMigrations spanner.Migrations
 	for _, m := range migrations {
-		if m.IsRepeatable {
+		switch {
+		case options.TargetVersion > 0 && (m.IsRepeatable || m.Version > options.TargetVersion):
+			continue
+		case m.IsRepeatable:
 			repeatableMigrations = append(repeatableMigrations, m)
-		} else {
+		default:
 			versionedMigrations = append(versionedMigrations, m)
 		}
 	}
 
-	if err = client.EnsureMigrationTable(ctx, options.VersionTableName); err != nil {
+	if err = client.EnsureMigrationTable(ctx, options.VersionTableName, options.LockTableName); err != nil {
 		return err
 	}
 
+	// a database on the emulator is disposable, so skipping the migrations for good does no harm
+	if options.Environment == "" && !spanner.OnEmulator() {
+		if err := requireEnvironment(ctx, client, versionedMigrations, options.VersionTableName); err != nil {
+			return err
+		}
+	}
+
 	status, err := client.DetermineUpgradeStatus(ctx, options.VersionTableName)
 	if err != nil {
 		return err
@@ -127,8 +199,8 @@ func MigrateUp(ctx context.Context, client *spanner.Client, migrationsDir string
 		return errors.New("migration in undetermined state")
 	}
 
+	repeatableTableName := spanner.RepeatableHistoryTableName(options.VersionTableName)
 	if len(repeatableMigrations) > 0 {
-		repeatableTableName := spanner.RepeatableHistoryTableName(options.VersionTableName)
 		if err := client.EnsureRepeatableMigrationTable(ctx, repeatableTableName); err != nil {
 			return err
 		}
@@ -139,13 +211,124 @@ func MigrateUp(ctx context.Context, client *spanner.Client, migrationsDir string
 		maps.Copy(migrationsOutput, repeatableOutput)
 	}
 
+	if options.TargetVersion > 0 {
+		if options.PrintRowsAffected {
+			fmt.Fprint(client.Output(), migrationsOutput.String())
+		}
+		return nil
+	}
+
+	if err := reportDeletedRepeatables(ctx, client, repeatableTableName, repeatableMigrations, options.PruneRepeatables); err != nil {
+		return err
+	}
+
+	if options.Seed {
+		seedOutput, err := seed(ctx, client, migrationsDir, options)
+		if err != nil {
+			return err
+		}
+		maps.Copy(migrationsOutput, seedOutput)
+	}
+
 	if options.PrintRowsAffected {
-		fmt.Print(migrationsOutput.String())
+		fmt.Fprint(client.Output(), migrationsOutput.String())
 	}
 
 	return nil
 }
 
+// requireEnvironment returns an error if migrations scoped to environments are pending, as without an environment they
+// would be recorded as skipped and never applied.
+func requireEnvironment(ctx context.Context, client *spanner.Client, migrations spanner.Migrations, versionTableName string) error {
+	history, err := client.GetMigrationHistory(ctx, versionTableName)
+	if err != nil {
+		return err
+	}
+
+	pending := migrations.PendingEnvironmentScoped(history)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	fileNames := make([]string, 0, len(pending))
+	for _, m := range pending {
+		fileNames = append(fileNames, m.FileName)
+	}
+	return fmt.Errorf("migrations %s are scoped to environments with the Environments directive, set the environment to apply or skip them", strings.Join(fileNames, ", "))
+}
+
+// reportDeletedRepeatables prints the repeatable migrations in the history whose file has been deleted, and deletes
+// their history if prune is set.
+func reportDeletedRepeatables(ctx context.Context, client *spanner.Client, tableName string, repeatableMigrations spanner.Migrations, prune bool) error {
+	history, err := client.GetRepeatableMigrationHistory(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	deleted := deletedRepeatables(history, repeatableMigrations)
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	if prune {
+		if err := client.DeleteRepeatableMigrationHistory(ctx, tableName, deleted); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range deleted {
+		if prune {
+			fmt.Fprintf(client.Output(), "%s/deleted (history removed)\n", name)
+		} else {
+			fmt.Fprintf(client.Output(), "%s/deleted (history kept)\n", name)
+		}
+	}
+
+	return nil
+}
+
+// deletedRepeatables returns the sorted names of the repeatable migrations in the history that have no file.
+func deletedRepeatables(history []spanner.RepeatableMigrationHistoryRecord, repeatableMigrations spanner.Migrations) []string {
+	exists := make(map[string]bool, len(repeatableMigrations))
+	for _, m := range repeatableMigrations {
+		exists[m.Name] = true
+	}
+
+	var deleted []string
+	for _, h := range history {
+		if !exists[h.Name] {
+			deleted = append(deleted, h.Name)
+		}
+	}
+	sort.Strings(deleted)
+
+	return deleted
+}
+
+// printInterruptedSummary prints the state of the database after migrate up was stopped or cancelled.
+func printInterruptedSummary(ctx context.Context, client *spanner.Client, versionTableName string, cancelled bool) {
+	if cancelled {
+		fmt.Fprintln(client.Output(), "Migrations cancelled.")
+	} else {
+		fmt.Fprintln(client.Output(), "Migrations stopped after the current migration.")
+	}
+
+	version, dirty, err := client.GetSchemaMigrationVersion(ctx, versionTableName)
+	if err != nil {
+		fmt.Fprintf(client.Output(), "failed to get the database version: %v\n", err)
+		return
+	}
+
+	if !dirty {
+		fmt.Fprintf(client.Output(), "Database version %d is clean. Run migrate up again to apply the remaining migrations.\n", version)
+		return
+	}
+
+	fmt.Fprintf(client.Output(), "Database version %d is dirty. DDL statements committed before the operation was cancelled are not "+
+		"rolled back. Run migrate wait if the operation is still running, otherwise migrate repair to see which "+
+		"statements were applied.\n", version)
+}
+
 // MigrateHistory prints the migration history.
 // The relevant options are LockTableName, LockIdentifier and VersionTableName.
 func MigrateHistory(ctx context.Context, client *spanner.Client, opts ...MigrateOpt) error {
@@ -173,10 +356,10 @@ func MigrateHistory(ctx context.Context, client *spanner.Client, opts ...Migrate
 	})
 
 	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
-	_, _ = fmt.Fprintln(writer, "Version\tDirty\tCreated\tModified")
+	_, _ = fmt.Fprintln(writer, "Version\tDirty\tCreated\tModified\tSkipped")
 	for i := range history {
 		h := history[i]
-		_, _ = fmt.Fprintf(writer, "%d\t%v\t%v\t%v\n", h.Version, h.Dirty, h.Created, h.Modified)
+		_, _ = fmt.Fprintf(writer, "%d\t%v\t%v\t%v\t%s\n", h.Version, h.Dirty, h.Created, h.Modified, h.SkipReason.StringVal)
 	}
 	_ = writer.Flush()
 
@@ -202,9 +385,111 @@ func MigrateHistory(ctx context.Context, client *spanner.Client, opts ...Migrate
 	return nil
 }
 
+// MigrateStatus prints the status of each migration in the migrations directory: applied, skipped, dirty or pending.
+// Pending migrations that will be skipped in the environment are shown with the reason.
+// The relevant options are LockTableName, LockIdentifier, VersionTableName, SkipVersions and Environment.
+func MigrateStatus(ctx context.Context, client *spanner.Client, migrationsDir string, opts ...MigrateOpt) error {
+	options := defaultMigrateOptions()
+	for _, optFn := range opts {
+		if err := optFn(options); err != nil {
+			return err
+		}
+	}
+	lock, err := client.GetMigrationLock(ctx, options.LockTableName, options.LockIdentifier)
+	defer lock.Release()
+	if err != nil {
+		return err
+	}
+	if !lock.Success {
+		return fmt.Errorf("lock taken by another process %s which expires %v", lock.LockIdentifier, lock.Expiry)
+	}
+
+	migrations, err := spanner.LoadMigrations(migrationsDir, options.SkipVersions, false, spanner.PlaceholderOptions{ReplacementEnabled: false})
+	if err != nil {
+		return err
+	}
+	migrations.SkipEnvironments(options.Environment)
+
+	// every migration is pending on a database without a history table
+	history, err := client.GetMigrationHistory(ctx, options.VersionTableName)
+	var se *spanner.Error
+	if errors.As(err, &se) && se.Code == spanner.ErrorCodeGetMigrationVersion {
+		history, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	applied := make(map[uint]spanner.MigrationHistoryRecord, len(history))
+	for _, h := range history {
+		applied[uint(h.Version)] = h
+	}
+
+	repeatableHistory, err := client.GetRepeatableMigrationHistory(ctx, spanner.RepeatableHistoryTableName(options.VersionTableName))
+	if err != nil {
+		return err
+	}
+	checksums := make(map[string]string, len(repeatableHistory))
+	for _, h := range repeatableHistory {
+		checksums[h.Name] = h.Checksum
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	_, _ = fmt.Fprintln(writer, "Migration\tStatus")
+	var repeatableMigrations spanner.Migrations
+	for _, m := range migrations {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\n", m.FileName, migrationStatus(m, applied, checksums))
+		if m.IsRepeatable {
+			repeatableMigrations = append(repeatableMigrations, m)
+		}
+	}
+	for _, name := range deletedRepeatables(repeatableHistory, repeatableMigrations) {
+		_, _ = fmt.Fprintf(writer, "R__%s\tdeleted\n", name)
+	}
+	_ = writer.Flush()
+
+	return nil
+}
+
+func migrationStatus(m *spanner.Migration, applied map[uint]spanner.MigrationHistoryRecord, checksums map[string]string) string {
+	var status string
+	if m.IsRepeatable {
+		checksum, ok := checksums[m.Name]
+		switch {
+		case !ok:
+			status = "pending"
+		case checksum != m.Checksum:
+			status = "changed"
+		case m.Directives.RunAlways:
+			status = "applied, runs always"
+		default:
+			return "applied"
+		}
+	} else {
+		h, ok := applied[m.Version]
+		switch {
+		case !ok:
+			status = "pending"
+		case h.Dirty:
+			return "dirty"
+		case h.SkipReason.Valid:
+			return fmt.Sprintf("skipped (%s)", h.SkipReason.StringVal)
+		default:
+			return "applied"
+		}
+	}
+
+	if m.SkipReason != "" {
+		return fmt.Sprintf("%s, will be skipped (%s)", status, m.SkipReason)
+	}
+	return status
+}
+
 // MigrateRepair repairs the migration history table if it in a dirty state after a failed migration. After cleaning the
 // schema manually run this step to remove the latest migration from the history table.
-// The relevant options are LockTableName, LockIdentifier and VersionTableName.
+// If MigrationsDir is set, a report of the statements applied by the failed DDL operation is printed first. If
+// WriteRemaining is also set, the statements that were not applied are written to a new migration and the dirty
+// versions are marked as applied instead of being removed.
+// The relevant options are LockTableName, LockIdentifier, VersionTableName, MigrationsDir and WriteRemaining.
 func MigrateRepair(ctx context.Context, client *spanner.Client, opts ...MigrateOpt) error {
 	options := defaultMigrateOptions()
 	for _, optFn := range opts {
@@ -221,10 +506,17 @@ func MigrateRepair(ctx context.Context, client *spanner.Client, opts ...MigrateO
 		return fmt.Errorf("lock taken by another process %s which expires %v", lock.LockIdentifier, lock.Expiry)
 	}
 
-	if err = client.EnsureMigrationTable(ctx, options.VersionTableName); err != nil {
+	if err = client.EnsureMigrationTable(ctx, options.VersionTableName, options.LockTableName); err != nil {
 		return err
 	}
 
+	if options.MigrationsDir != "" {
+		applied, err := repairWithReport(ctx, client, options)
+		if err != nil || applied {
+			return err
+		}
+	}
+
 	if err := client.RepairMigration(ctx, options.VersionTableName); err != nil {
 		return err
 	}
@@ -232,6 +524,40 @@ func MigrateRepair(ctx context.Context, client *spanner.Client, opts ...MigrateO
 	return nil
 }
 
+// MigrateWait waits for the DDL operation of a dirty migration to complete, for example after wrench was interrupted
+// during a long-running index backfill. The version is marked clean if the operation succeeded.
+// The relevant options are LockTableName, LockIdentifier and VersionTableName.
+func MigrateWait(ctx context.Context, client *spanner.Client, opts ...MigrateOpt) error {
+	options := defaultMigrateOptions()
+	for _, optFn := range opts {
+		if err := optFn(options); err != nil {
+			return err
+		}
+	}
+	lock, err := client.GetMigrationLock(ctx, options.LockTableName, options.LockIdentifier)
+	defer lock.Release()
+	if err != nil {
+		return err
+	}
+	if !lock.Success {
+		return fmt.Errorf("lock taken by another process %s which expires %v", lock.LockIdentifier, lock.Expiry)
+	}
+
+	if err = client.EnsureMigrationTable(ctx, options.VersionTableName, options.LockTableName); err != nil {
+		return err
+	}
+
+	resumed, err := client.ResumeMigrations(ctx, options.VersionTableName)
+	if err != nil {
+		return err
+	}
+	if len(resumed) == 0 {
+		fmt.Fprintln(client.Output(), "no pending operation")
+	}
+
+	return nil
+}
+
 // MigrateSetupLock sets up the migration lock table.
 // The relevant options are LockTableName.
 func MigrateSetupLock(ctx context.Context, client *spanner.Client, opts ...MigrateOpt) error {
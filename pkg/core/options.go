@@ -1,6 +1,17 @@
 package core
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
 
 type migrateOptions struct {
 	// LockTableName is the name of the table that stores the lock.
@@ -13,6 +24,9 @@ type migrateOptions struct {
 	SkipVersions []uint
 	// Limit is the maximum number of migrations to apply.
 	Limit int
+	// TargetVersion is the last version to apply, if set. Repeatable migrations and seeds are not applied as they
+	// track the latest files rather than a version.
+	TargetVersion uint
 	// PartitionedDMLConcurrency is the concurrency level for applying partitioned DML statements.
 	PartitionedDMLConcurrency int
 	// DetectPartitionedDML is whether to detect partitioned DML statements for use with the PartitionedDML API.
@@ -20,11 +34,9 @@ type migrateOptions struct {
 	// PrintRowsAffected is whether to print the number of rows affected by each migration.
 	PrintRowsAffected bool
 
-	// Placeholders is map of placeholder variable names to placeholder values.
-	// Currently this only supports replacing the following placeholder keys:
-	// - ${PROJECT_ID}
-	// - ${INSTANCE_ID}
-	// - ${DATABASE_ID}
+	// Placeholders is map of placeholder variable names to placeholder values. Besides the default placeholders
+	// ${PROJECT_ID}, ${INSTANCE_ID} and ${DATABASE_ID}, any user-defined name is replaced, e.g. ${REGION}, and
+	// ${NAME:-default} is replaced with the default when the name has no value.
 	Placeholders map[string]string
 	// PlaceholdersEnabled is used to enable or disable placeholder substitition within migration files.
 	PlaceholdersEnabled bool
@@ -34,6 +46,34 @@ type migrateOptions struct {
 	// FFMigrations enables fast-forward migrations by aggregating contiguous non-applied migrations
 	// of the same type into a single UpdateDatabaseDdlRequest.
 	FFMigrations bool
+
+	// MigrationsDir is the directory of the migration files, used to report the statements of dirty migrations.
+	MigrationsDir string
+	// WriteRemaining writes the statements not applied by a failed DDL operation to a new migration on repair.
+	WriteRemaining bool
+
+	// Environment is the environment being migrated. Migrations scoped to other environments with the Environments
+	// directive are skipped.
+	Environment string
+
+	// Seed applies the seed data files after the migrations.
+	Seed bool
+
+	// PruneRepeatables deletes the history of repeatable migrations whose file has been deleted.
+	PruneRepeatables bool
+
+	// StaticDataTables are the tables whose data is written to the baseline by Squash, or copied to the shadow database
+	// by Rehearse.
+	StaticDataTables []string
+	// StaticDataOrderBy is the custom ORDER BY clause of static data tables, by table name.
+	StaticDataOrderBy map[string]string
+
+	// FleetConcurrency is the number of databases migrated at the same time by FleetMigrate.
+	FleetConcurrency int
+	// ContinueOnError continues to migrate the other databases of a fleet after a database fails.
+	ContinueOnError bool
+	// FleetStop is closed to finish the databases in progress and skip the remaining databases of a fleet.
+	FleetStop <-chan struct{}
 }
 
 func defaultMigrateOptions() *migrateOptions {
@@ -43,6 +83,7 @@ func defaultMigrateOptions() *migrateOptions {
 		LockTableName:        "SchemaMigrationsLock",
 		VersionTableName:     "SchemaMigrations",
 		Limit:                -1,
+		FleetConcurrency:     1,
 		DetectPartitionedDML: false,
 		Placeholders:         map[string]string{},
 		PlaceholdersEnabled:  false,
@@ -85,6 +126,14 @@ func WithLimit(limit int) MigrateOpt {
 	}
 }
 
+// WithTargetVersion sets the last version to apply. Repeatable migrations and seeds are not applied.
+func WithTargetVersion(version uint) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.TargetVersion = version
+		return nil
+	}
+}
+
 // WithVersionTable sets the name of the table that stores the version.
 func WithVersionTable(name string) MigrateOpt {
 	return func(opt *migrateOptions) error {
@@ -117,17 +166,39 @@ func WithPrintRowsAffected(val bool) MigrateOpt {
 	}
 }
 
-// WithPlaceholders sets whether placeholders are enabled and the placeholder key to value mapping.
+// WithDefaultPlaceholders sets whether placeholders are enabled and adds the default placeholders. The defaults do not
+// override placeholders set by WithPlaceholders.
 func WithDefaultPlaceholders(enabled bool, projectID, instanceID, databaseID string) MigrateOpt {
 	return func(opt *migrateOptions) error {
 		opt.PlaceholdersEnabled = enabled
 		if enabled {
-			opt.Placeholders = DefaultPlaceholders(projectID, instanceID, databaseID)
+			for k, v := range DefaultPlaceholders(projectID, instanceID, databaseID) {
+				if _, ok := opt.Placeholders[k]; !ok {
+					opt.Placeholders[k] = v
+				}
+			}
 		}
 		return nil
 	}
 }
 
+// WithPlaceholdersEnabled sets whether placeholders are enabled without adding the default placeholders, e.g. for
+// FleetMigrate, which adds the default placeholders of each database.
+func WithPlaceholdersEnabled(enabled bool) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.PlaceholdersEnabled = enabled
+		return nil
+	}
+}
+
+// WithPlaceholders adds user-defined placeholders, which take precedence over the default placeholders.
+func WithPlaceholders(placeholders map[string]string) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		maps.Copy(opt.Placeholders, placeholders)
+		return nil
+	}
+}
+
 func DefaultPlaceholders(projectID, instanceID, databaseID string) map[string]string {
 	return map[string]string{
 		"PROJECT_ID":  projectID,
@@ -153,17 +224,128 @@ func WithFFMigrations(enabled bool) MigrateOpt {
 	}
 }
 
+// WithRepairReport sets the migrations directory used to report which statements of the dirty migrations were
+// applied, and whether to write the statements that were not applied to a new migration.
+func WithRepairReport(migrationsDir string, writeRemaining bool) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.MigrationsDir = migrationsDir
+		opt.WriteRemaining = writeRemaining
+		return nil
+	}
+}
+
+// WithEnvironment sets the environment being migrated. Migrations scoped to other environments with the Environments
+// directive are recorded as skipped in the history without being executed.
+func WithEnvironment(environment string) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.Environment = environment
+		return nil
+	}
+}
+
+// WithSeed sets whether to apply the seed data files after the migrations.
+func WithSeed(val bool) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.Seed = val
+		return nil
+	}
+}
+
+// WithPruneRepeatables sets whether to delete the history of repeatable migrations whose file has been deleted. Deleted
+// repeatable migrations are reported either way.
+func WithPruneRepeatables(val bool) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.PruneRepeatables = val
+		return nil
+	}
+}
+
+// WithStaticDataTables sets the tables whose data is written to the baseline by Squash or copied to the shadow
+// database by Rehearse, and the custom ORDER BY clause
+// of any of the tables.
+func WithStaticDataTables(tables []string, orderBy map[string]string) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.StaticDataTables = tables
+		opt.StaticDataOrderBy = orderBy
+		return nil
+	}
+}
+
+// WithFleetConcurrency sets the number of databases migrated at the same time by FleetMigrate. Defaults to 1.
+func WithFleetConcurrency(concurrency int) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		if concurrency < 1 {
+			return fmt.Errorf("fleet concurrency must be at least 1, but got %d", concurrency)
+		}
+		opt.FleetConcurrency = concurrency
+		return nil
+	}
+}
+
+// WithContinueOnError sets whether FleetMigrate continues to migrate the other databases after a database fails. By
+// default the databases that have not started are skipped.
+func WithContinueOnError(val bool) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.ContinueOnError = val
+		return nil
+	}
+}
+
+// WithFleetStop sets a channel that is closed to stop FleetMigrate. The databases in progress are finished and the
+// remaining databases are skipped.
+func WithFleetStop(stop <-chan struct{}) MigrateOpt {
+	return func(opt *migrateOptions) error {
+		opt.FleetStop = stop
+		return nil
+	}
+}
+
+// SequenceStrategy is how the version of the next migration is generated.
+type SequenceStrategy string
+
+const (
+	// SequenceSequential uses the next version after the latest migration, rounded up to the interval. This is the
+	// default.
+	SequenceSequential SequenceStrategy = "sequential"
+	// SequenceTimestamp uses the current UTC time as the version in the format YYYYMMDDHHMMSS, e.g. 20240102150405.
+	// Versions from different branches rarely collide. Timestamps are greater than sequential versions, so a directory
+	// of sequential migrations can switch to timestamps without renaming any files.
+	SequenceTimestamp SequenceStrategy = "timestamp"
+)
+
+// ParseSequenceStrategy parses sequential or timestamp.
+func ParseSequenceStrategy(s string) (SequenceStrategy, error) {
+	switch strategy := SequenceStrategy(s); strategy {
+	case SequenceSequential, SequenceTimestamp:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid sequence strategy %q, must be one of sequential or timestamp", s)
+	}
+}
+
 type migrationSequenceOptions struct {
 	// Interval is the interval between the migration sequences.
 	Interval uint
 	// ZeroPrefixLength is the length of the zero digit prefix for the migration sequence.
 	ZeroPrefixLength int
+	// Strategy is how the next version is generated.
+	Strategy SequenceStrategy
+	// Now returns the current time for the timestamp strategy.
+	Now func() time.Time
+	// Template is the contents of the new migration file.
+	Template string
+	// Repeatable creates a repeatable migration instead of the next version.
+	Repeatable bool
+	// Subdirectory is the subdirectory of the migrations directory to create the migration file in.
+	Subdirectory string
 }
 
 func defaultSequenceOptions() migrationSequenceOptions {
 	return migrationSequenceOptions{
 		Interval:         10,
 		ZeroPrefixLength: 6,
+		Strategy:         SequenceSequential,
+		Now:              time.Now,
 	}
 }
 
@@ -187,3 +369,51 @@ func WithZeroPrefixLength(length int) MigrationSequenceOpt {
 		return nil
 	}
 }
+
+// WithSequenceStrategy sets how the version of the next migration is generated. The interval is not used by the
+// timestamp strategy.
+func WithSequenceStrategy(strategy SequenceStrategy) MigrationSequenceOpt {
+	return func(opt *migrationSequenceOptions) error {
+		if _, err := ParseSequenceStrategy(string(strategy)); err != nil {
+			return err
+		}
+		opt.Strategy = strategy
+		return nil
+	}
+}
+
+// WithTemplate sets the contents of the new migration file, e.g. from MigrationTemplate. By default the file is empty.
+func WithTemplate(content string) MigrationSequenceOpt {
+	return func(opt *migrationSequenceOptions) error {
+		opt.Template = content
+		return nil
+	}
+}
+
+// WithRepeatable creates a repeatable migration, R__name.sql, instead of the next version.
+func WithRepeatable(repeatable bool) MigrationSequenceOpt {
+	return func(opt *migrationSequenceOptions) error {
+		opt.Repeatable = repeatable
+		return nil
+	}
+}
+
+// WithSubdirectory creates the migration file in a subdirectory of the migrations directory, e.g. 2026. The
+// subdirectory is created if it does not exist. The version still follows the migrations in all directories.
+func WithSubdirectory(subdirectory string) MigrationSequenceOpt {
+	return func(opt *migrationSequenceOptions) error {
+		if subdirectory == "" {
+			opt.Subdirectory = ""
+			return nil
+		}
+		if !filepath.IsLocal(subdirectory) {
+			return fmt.Errorf("subdirectory %s must be inside the migrations directory", subdirectory)
+		}
+		clean := filepath.Clean(subdirectory)
+		if slices.Contains(strings.Split(filepath.ToSlash(clean), "/"), spanner.ArchiveDirName) {
+			return fmt.Errorf("subdirectory %s is not loaded as it is an archive directory", subdirectory)
+		}
+		opt.Subdirectory = clean
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// Rehearse applies the pending migrations of the target database to a shadow database, e.g. on the emulator, so that
+// failures are reported before the target is changed. The shadow database is created with the schema and proto
+// descriptors of the target, and the data of its migration tracking tables so that only the pending migrations are
+// applied. The data of the tables set by WithStaticDataTables is also copied. The target database is only read.
+// The relevant options are the same as MigrateUp.
+func Rehearse(ctx context.Context, target, shadow *spanner.Client, migrationsDir string, opts ...MigrateOpt) error {
+	options := defaultMigrateOptions()
+	for _, optFn := range opts {
+		if err := optFn(options); err != nil {
+			return err
+		}
+	}
+
+	snapshot, err := target.LoadSnapshot(ctx, options.VersionTableName, options.StaticDataTables, options.StaticDataOrderBy)
+	if err != nil {
+		return err
+	}
+	if err := shadow.CreateDatabaseFromSnapshot(ctx, snapshot); err != nil {
+		return err
+	}
+	// the snapshot has the lock table without its row, which the lock is taken on
+	if err := shadow.SetupMigrationLock(ctx, options.LockTableName); err != nil {
+		return err
+	}
+
+	fmt.Println("Rehearsing the pending migrations on the shadow database")
+	if err := MigrateUp(ctx, shadow, migrationsDir, opts...); err != nil {
+		return fmt.Errorf("rehearsal failed, the target database was not changed: %w", err)
+	}
+	fmt.Println("Rehearsal succeeded")
+
+	return nil
+}
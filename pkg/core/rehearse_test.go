@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+func TestRehearse(t *testing.T) {
+	ctx := context.Background()
+
+	migrationsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, "000001_create_singers.sql"),
+		[]byte("CREATE TABLE Singers (SingerID STRING(36) NOT NULL) PRIMARY KEY (SingerID);\n"), 0o644))
+
+	config := testDatabases(t, ctx, 1)[0]
+	target, err := spanner.NewClient(ctx, config)
+	require.NoError(t, err)
+	defer target.Close()
+
+	// the target has the lock table, which is copied to the shadow
+	require.NoError(t, MigrateUp(ctx, target, migrationsDir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, "000002_create_albums.sql"),
+		[]byte("CREATE TABLE Albums (AlbumID STRING(36) NOT NULL) PRIMARY KEY (AlbumID);\n"), 0o644))
+
+	shadowConfig := *config
+	shadowConfig.Database += "-shadow"
+	shadow, err := spanner.NewClient(ctx, &shadowConfig)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, shadow.DropDatabase(ctx))
+		shadow.Close()
+	}()
+
+	require.NoError(t, Rehearse(ctx, target, shadow, migrationsDir))
+
+	version, dirty, err := shadow.GetSchemaMigrationVersion(ctx, "SchemaMigrations")
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), version)
+	assert.False(t, dirty)
+
+	// the target is not changed
+	version, _, err = target.GetSchemaMigrationVersion(ctx, "SchemaMigrations")
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+}
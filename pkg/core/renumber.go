@@ -0,0 +1,128 @@
+package core
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// Renumbered is a migration file renamed by RenumberMigrations.
+type Renumbered struct {
+	From string
+	To   string
+}
+
+// RenumberMigrations renumbers the named versioned migrations in dir so that they follow version after, in version
+// order, using the same sequence options as CreateMigrationFile. The file names are relative to dir, or to the first
+// directory if dir is a list of directories joined by os.PathListSeparator, and may be in subdirectories. The names and
+// suffixes of the files are preserved, and their companion assert files are renamed with them. Versions used by other
+// migration files or recorded as applied are passed over. A migration whose current version is recorded as applied is
+// not renumbered, as the database would apply it again under the new version.
+func RenumberMigrations(dir string, fileNames []string, after uint, applied []uint, opts ...MigrationSequenceOpt) ([]Renumbered, error) {
+	options := defaultSequenceOptions()
+	for _, optFn := range opts {
+		if err := optFn(&options); err != nil {
+			return nil, err
+		}
+	}
+
+	root := spanner.MigrationsDirs(dir)[0]
+	type local struct {
+		fileName string
+		version  uint
+	}
+	var locals []local
+	renumbering := make(map[string]bool, len(fileNames))
+	for _, fileName := range fileNames {
+		version, ok := spanner.MigrationVersion(fileName)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a versioned migration", fileName)
+		}
+		if slices.Contains(applied, version) {
+			return nil, fmt.Errorf("migration %s cannot be renumbered as version %d is applied in the database", fileName, version)
+		}
+		locals = append(locals, local{fileName: fileName, version: version})
+		renumbering[filepath.Join(root, fileName)] = true
+	}
+	slices.SortFunc(locals, func(a, b local) int {
+		return cmp.Or(cmp.Compare(a.version, b.version), strings.Compare(a.fileName, b.fileName))
+	})
+
+	// versions of the other migration files and the applied versions are taken
+	taken := make(map[uint]bool, len(applied))
+	for _, v := range applied {
+		taken[v] = true
+	}
+	files, err := spanner.ListMigrationFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if v, ok := spanner.MigrationVersion(f.FileName); ok && !renumbering[f.Path()] {
+			taken[v] = true
+		}
+	}
+
+	var renumbered []Renumbered
+	v := after
+	for _, l := range locals {
+		v = nextVersion(v, options)
+		for taken[v] {
+			v = nextVersion(v, options)
+		}
+
+		suffix := strings.TrimLeftFunc(filepath.Base(l.fileName), unicode.IsDigit)
+		to := filepath.Join(filepath.Dir(l.fileName), fmt.Sprintf("%0*d%s", options.ZeroPrefixLength, v, suffix))
+		if to != l.fileName {
+			renumbered = append(renumbered, Renumbered{From: l.fileName, To: to})
+		}
+	}
+
+	if err := renameMigrations(root, renumbered); err != nil {
+		return nil, err
+	}
+
+	if err := updateSum(dir); err != nil {
+		return nil, err
+	}
+
+	return renumbered, nil
+}
+
+// renameMigrations renames the migration files and their companion assert files. The files are first moved to
+// temporary names, so that a file can be renamed to the previous name of another renamed file.
+func renameMigrations(dir string, renumbered []Renumbered) error {
+	const tmpSuffix = ".renumber"
+
+	var moves [][2]string
+	for _, r := range renumbered {
+		moves = append(moves, [2]string{r.From, r.To})
+		if _, err := os.Stat(filepath.Join(dir, spanner.AssertFileName(r.From))); err == nil {
+			moves = append(moves, [2]string{spanner.AssertFileName(r.From), spanner.AssertFileName(r.To)})
+		}
+	}
+
+	for _, m := range moves {
+		if err := os.Rename(filepath.Join(dir, m[0]), filepath.Join(dir, m[0]+tmpSuffix)); err != nil {
+			return err
+		}
+	}
+	for _, m := range moves {
+		to := filepath.Join(dir, m[1])
+		if _, err := os.Stat(to); !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot rename %s to %s as the file already exists", m[0], m[1])
+		}
+		if err := os.Rename(filepath.Join(dir, m[0]+tmpSuffix), to); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
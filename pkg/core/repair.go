@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// dirtyOperation is a group of dirty migrations that were applied by the same DDL operation, or a single dirty
+// migration when no operation was recorded.
+type dirtyOperation struct {
+	name      string
+	records   []spanner.MigrationHistoryRecord
+	committed int64
+	known     bool
+	failure   string
+}
+
+func groupDirtyOperations(dirty []spanner.MigrationHistoryRecord) []*dirtyOperation {
+	var ops []*dirtyOperation
+	byName := map[string]*dirtyOperation{}
+	for _, r := range dirty {
+		name := r.OperationName.StringVal
+		op, ok := byName[name]
+		if !ok || !r.OperationName.Valid {
+			op = &dirtyOperation{name: name}
+			ops = append(ops, op)
+			if r.OperationName.Valid {
+				byName[name] = op
+			}
+		}
+		op.records = append(op.records, r)
+		if r.CommittedStatements.Valid {
+			op.committed = r.CommittedStatements.Int64
+			op.known = true
+		}
+		if r.FailureMessage.Valid {
+			op.failure = r.FailureMessage.StringVal
+		}
+	}
+	return ops
+}
+
+// printRepairReport writes which statements of the dirty migrations were applied by the failed DDL operation and which
+// were not. The statements of an operation span its migrations in version order, so the first committed statements
+// are the ones that were applied. Returns the statements that were not applied, or false if that is unknown.
+func printRepairReport(w io.Writer, dirty []spanner.MigrationHistoryRecord, migrations spanner.Migrations) ([]string, bool) {
+	byVersion := map[uint]*spanner.Migration{}
+	for _, m := range migrations {
+		if !m.IsRepeatable {
+			byVersion[m.Version] = m
+		}
+	}
+
+	var remaining []string
+	allKnown := true
+	for _, op := range groupDirtyOperations(dirty) {
+		if op.name != "" {
+			fmt.Fprintf(w, "Operation %s\n", op.name)
+		}
+		if op.failure != "" {
+			fmt.Fprintf(w, "Failed: %s\n", op.failure)
+		}
+		allKnown = allKnown && op.known
+
+		var index int64
+		for _, r := range op.records {
+			m, ok := byVersion[uint(r.Version)]
+			if !ok {
+				fmt.Fprintf(w, "Version %d: migration file not found\n", r.Version)
+				allKnown = false
+				continue
+			}
+
+			fmt.Fprintf(w, "Version %d (%s)\n", m.Version, m.FileName)
+			for _, stmt := range m.Statements {
+				status := "unknown"
+				if op.known && index < op.committed {
+					status = "applied"
+				} else if op.known {
+					status = "not applied"
+					remaining = append(remaining, stmt)
+				}
+				fmt.Fprintf(w, "  %-12s %s\n", status, strings.Join(strings.Fields(stmt), " "))
+				index++
+			}
+		}
+	}
+
+	return remaining, allKnown
+}
+
+// writeRemainingMigration writes the statements that were not applied to a new migration file named after the first
+// dirty migration.
+func writeRemainingMigration(dir string, dirty []spanner.MigrationHistoryRecord, migrations spanner.Migrations, remaining []string) (string, error) {
+	name := "remaining"
+	for _, m := range migrations {
+		if !m.IsRepeatable && int64(m.Version) == dirty[0].Version && m.Name != "" {
+			name = m.Name + "_remaining"
+			break
+		}
+	}
+
+	// the contents are written by CreateMigrationFile so that the wrench.sum manifest has the hash of the final file
+	content := strings.Join(remaining, ";\n\n") + ";\n"
+	return CreateMigrationFile(dir, name, WithTemplate(content))
+}
+
+// repairWithReport prints the repair report for the dirty migrations. If options.WriteRemaining is set the statements
+// that were not applied are written to a new migration and the dirty versions are marked as applied. Returns true if
+// the dirty versions were marked as applied.
+func repairWithReport(ctx context.Context, client *spanner.Client, options *migrateOptions) (bool, error) {
+	dirty, err := client.GetDirtyMigrations(ctx, options.VersionTableName)
+	if err != nil {
+		return false, err
+	}
+	if len(dirty) == 0 {
+		return false, nil
+	}
+
+	migrations, err := spanner.LoadMigrations(options.MigrationsDir, nil, false, spanner.PlaceholderOptions{ReplacementEnabled: false})
+	if err != nil {
+		return false, err
+	}
+
+	remaining, known := printRepairReport(client.Output(), dirty, migrations)
+	if !options.WriteRemaining {
+		return false, nil
+	}
+	if !known {
+		return false, errors.New("the applied statements of the dirty migrations are unknown, cannot write the remaining statements")
+	}
+
+	var versions []uint
+	for _, r := range dirty {
+		versions = append(versions, uint(r.Version))
+	}
+
+	if len(remaining) > 0 {
+		filename, err := writeRemainingMigration(options.MigrationsDir, dirty, migrations, remaining)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(client.Output(), "wrote remaining statements to %s\n", filename)
+	}
+
+	if err := client.MarkMigrationsApplied(ctx, options.VersionTableName, versions); err != nil {
+		return false, err
+	}
+	fmt.Fprintf(client.Output(), "marked versions %v as applied\n", versions)
+
+	return true, nil
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cloudspanner "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+func Test_writeRemainingMigrationSum(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000001_add_indexes.sql"), []byte("CREATE INDEX A ON Singers(A);\nCREATE INDEX B ON Singers(B);\n"), 0o644))
+	_, err := WriteSum(dir)
+	require.NoError(t, err)
+
+	migrations, err := spanner.LoadMigrations(dir, nil, false, spanner.PlaceholderOptions{})
+	require.NoError(t, err)
+	dirty := []spanner.MigrationHistoryRecord{{Version: 1, Dirty: true}}
+
+	_, err = writeRemainingMigration(dir, dirty, migrations, []string{"CREATE INDEX B ON Singers(B)"})
+	require.NoError(t, err)
+
+	// the manifest has the hash of the written statements rather than an empty file
+	assert.NoError(t, ValidateSum(dir))
+}
+
+func Test_groupDirtyOperations(t *testing.T) {
+	op := func(name string) cloudspanner.NullString {
+		return cloudspanner.NullString{StringVal: name, Valid: true}
+	}
+	committed := func(n int64) cloudspanner.NullInt64 { return cloudspanner.NullInt64{Int64: n, Valid: true} }
+
+	type group struct {
+		name      string
+		versions  []int64
+		committed int64
+		known     bool
+		failure   string
+	}
+	tests := map[string]struct {
+		dirty []spanner.MigrationHistoryRecord
+		want  []group
+	}{
+		"operation across versions": {
+			dirty: []spanner.MigrationHistoryRecord{
+				{Version: 1, Dirty: true, OperationName: op("op1"), CommittedStatements: committed(3), FailureMessage: cloudspanner.NullString{StringVal: "duplicate index", Valid: true}},
+				{Version: 2, Dirty: true, OperationName: op("op1"), CommittedStatements: committed(3)},
+			},
+			want: []group{{name: "op1", versions: []int64{1, 2}, committed: 3, known: true, failure: "duplicate index"}},
+		},
+		"separate operations": {
+			dirty: []spanner.MigrationHistoryRecord{
+				{Version: 1, Dirty: true, OperationName: op("op1"), CommittedStatements: committed(0)},
+				{Version: 2, Dirty: true, OperationName: op("op2")},
+				{Version: 3, Dirty: true, OperationName: op("op1"), CommittedStatements: committed(0)},
+			},
+			want: []group{
+				{name: "op1", versions: []int64{1, 3}, known: true},
+				{name: "op2", versions: []int64{2}},
+			},
+		},
+		"no operation recorded": {
+			dirty: []spanner.MigrationHistoryRecord{
+				{Version: 1, Dirty: true},
+				{Version: 2, Dirty: true},
+			},
+			want: []group{
+				{versions: []int64{1}},
+				{versions: []int64{2}},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []group
+			for _, op := range groupDirtyOperations(tt.dirty) {
+				g := group{name: op.name, committed: op.committed, known: op.known, failure: op.failure}
+				for _, r := range op.records {
+					g.versions = append(g.versions, r.Version)
+				}
+				got = append(got, g)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_printRepairReport(t *testing.T) {
+	migrations := spanner.Migrations{
+		{Version: 1, Name: "add_indexes", FileName: "000001_add_indexes.sql", Statements: []string{"CREATE INDEX A ON Singers(A)", "CREATE INDEX B ON Singers(B)"}},
+		{Version: 2, Name: "add_albums", FileName: "000002_add_albums.sql", Statements: []string{"CREATE INDEX C ON Albums(C)"}},
+	}
+	op := cloudspanner.NullString{StringVal: "op1", Valid: true}
+
+	tests := map[string]struct {
+		dirty         []spanner.MigrationHistoryRecord
+		wantRemaining []string
+		wantKnown     bool
+		wantLines     []string
+	}{
+		"partially committed within a version": {
+			dirty: []spanner.MigrationHistoryRecord{
+				{Version: 1, Dirty: true, OperationName: op, CommittedStatements: cloudspanner.NullInt64{Int64: 1, Valid: true}, FailureMessage: cloudspanner.NullString{StringVal: "duplicate index", Valid: true}},
+			},
+			wantRemaining: []string{"CREATE INDEX B ON Singers(B)"},
+			wantKnown:     true,
+			wantLines: []string{
+				"Operation op1",
+				"Failed: duplicate index",
+				"Version 1 (000001_add_indexes.sql)",
+				"  applied      CREATE INDEX A ON Singers(A)",
+				"  not applied  CREATE INDEX B ON Singers(B)",
+			},
+		},
+		"partially committed across versions": {
+			dirty: []spanner.MigrationHistoryRecord{
+				{Version: 1, Dirty: true, OperationName: op, CommittedStatements: cloudspanner.NullInt64{Int64: 2, Valid: true}},
+				{Version: 2, Dirty: true, OperationName: op, CommittedStatements: cloudspanner.NullInt64{Int64: 2, Valid: true}},
+			},
+			wantRemaining: []string{"CREATE INDEX C ON Albums(C)"},
+			wantKnown:     true,
+			wantLines: []string{
+				"Operation op1",
+				"Version 1 (000001_add_indexes.sql)",
+				"  applied      CREATE INDEX A ON Singers(A)",
+				"  applied      CREATE INDEX B ON Singers(B)",
+				"Version 2 (000002_add_albums.sql)",
+				"  not applied  CREATE INDEX C ON Albums(C)",
+			},
+		},
+		"committed statements unknown": {
+			dirty: []spanner.MigrationHistoryRecord{
+				{Version: 2, Dirty: true},
+			},
+			wantKnown: false,
+			wantLines: []string{
+				"Version 2 (000002_add_albums.sql)",
+				"  unknown      CREATE INDEX C ON Albums(C)",
+			},
+		},
+		"migration file not found": {
+			dirty: []spanner.MigrationHistoryRecord{
+				{Version: 3, Dirty: true, OperationName: op, CommittedStatements: cloudspanner.NullInt64{Int64: 0, Valid: true}},
+			},
+			wantKnown: false,
+			wantLines: []string{
+				"Operation op1",
+				"Version 3: migration file not found",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			remaining, known := printRepairReport(&buf, tt.dirty, migrations)
+			assert.Equal(t, tt.wantRemaining, remaining)
+			assert.Equal(t, tt.wantKnown, known)
+			assert.Equal(t, tt.wantLines, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"))
+		})
+	}
+}
+
+func Test_writeRemainingMigration(t *testing.T) {
+	tests := map[string]struct {
+		migrations spanner.Migrations
+		remaining  []string
+		wantFile   string
+		wantBody   string
+	}{
+		"named after the first dirty migration": {
+			migrations: spanner.Migrations{{Version: 1, Name: "add_indexes"}},
+			remaining:  []string{"CREATE INDEX B ON Singers(B)", "CREATE INDEX C ON Singers(C)"},
+			wantFile:   "000010_add_indexes_remaining.sql",
+			wantBody:   "CREATE INDEX B ON Singers(B);\n\nCREATE INDEX C ON Singers(C);\n",
+		},
+		"migration without a name": {
+			migrations: spanner.Migrations{{Version: 1}},
+			remaining:  []string{"CREATE INDEX B ON Singers(B)"},
+			wantFile:   "000010_remaining.sql",
+			wantBody:   "CREATE INDEX B ON Singers(B);\n",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "000001.sql"), []byte("CREATE INDEX A ON Singers(A);\n"), 0o644))
+			dirty := []spanner.MigrationHistoryRecord{{Version: 1, Dirty: true}}
+
+			filename, err := writeRemainingMigration(dir, dirty, tt.migrations, tt.remaining)
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(dir, tt.wantFile), filename)
+
+			got, err := os.ReadFile(filename)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(got))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// Seed applies the seed data files in the migrations directory, e.g. S__users.sql, that have changed since they were
+// last applied. Seeds scoped to other environments with the Environments directive are skipped.
+func Seed(ctx context.Context, client *spanner.Client, migrationsDir string, opts ...MigrateOpt) error {
+	options := defaultMigrateOptions()
+	for _, optFn := range opts {
+		if err := optFn(options); err != nil {
+			return err
+		}
+	}
+	lock, err := client.GetMigrationLock(ctx, options.LockTableName, options.LockIdentifier)
+	defer lock.Release()
+	if err != nil {
+		return err
+	}
+	if !lock.Success {
+		return fmt.Errorf("lock taken by another process %s which expires %v", lock.LockIdentifier, lock.Expiry)
+	}
+
+	output, err := seed(ctx, client, migrationsDir, options)
+	if err != nil {
+		return err
+	}
+
+	if options.PrintRowsAffected {
+		fmt.Fprint(client.Output(), output.String())
+	}
+
+	return nil
+}
+
+func seed(ctx context.Context, client *spanner.Client, migrationsDir string, options *migrateOptions) (spanner.MigrationsOutput, error) {
+	seeds, err := spanner.LoadSeeds(migrationsDir, options.DetectPartitionedDML, spanner.PlaceholderOptions{Placeholders: options.Placeholders, ReplacementEnabled: options.PlaceholdersEnabled})
+	if err != nil {
+		return nil, err
+	}
+	seeds.SkipEnvironments(options.Environment)
+	if len(seeds) == 0 {
+		return nil, nil
+	}
+
+	seedTableName := spanner.SeedHistoryTableName(options.VersionTableName)
+	if err := client.EnsureRepeatableMigrationTable(ctx, seedTableName); err != nil {
+		return nil, err
+	}
+
+	return client.ExecuteRepeatableMigrations(ctx, seeds, seedTableName, options.PartitionedDMLConcurrency, options.ProtoDescriptors)
+}
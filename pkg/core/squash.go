@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// Squash replays the versioned migrations up to and including version upTo against the database, which must be a new
+// database such as the emulator, then writes the resulting schema and static data to a single baseline migration,
+// e.g. 0900_baseline.sql, and moves the replayed migration files to archiveDir. The wrench.sum manifest is updated if
+// the directory has one. The replayed versions are listed by the Squashed directive of the baseline, and databases that
+// have applied all of them record the baseline as applied without executing it. Returns the path of the baseline file.
+func Squash(ctx context.Context, client *spanner.Client, migrationsDir string, upTo uint, archiveDir string, opts ...MigrateOpt) (string, error) {
+	options := defaultMigrateOptions()
+	for _, optFn := range opts {
+		if err := optFn(options); err != nil {
+			return "", err
+		}
+	}
+
+	migrations, err := spanner.LoadMigrations(migrationsDir, nil, options.DetectPartitionedDML, spanner.PlaceholderOptions{Placeholders: options.Placeholders, ReplacementEnabled: options.PlaceholdersEnabled})
+	if err != nil {
+		return "", err
+	}
+
+	var squashed spanner.Migrations
+	for _, m := range migrations {
+		if m.IsRepeatable || m.Version > upTo {
+			continue
+		}
+		if len(m.Directives.Environments) > 0 || m.Directives.SkipOnEmulator || m.Directives.EmulatorReplacement != "" {
+			return "", fmt.Errorf("migration %s cannot be squashed as it is not applied the same way to every database", m.FileName)
+		}
+		// the values of the emulator would be written to the baseline and applied to every database
+		usesPlaceholders, err := m.UsesPlaceholders()
+		if err != nil {
+			return "", err
+		}
+		if usesPlaceholders {
+			return "", fmt.Errorf("migration %s cannot be squashed as it uses placeholders or a template, which are not applied the same way to every database", m.FileName)
+		}
+		squashed = append(squashed, m)
+	}
+	if len(squashed) == 0 {
+		return "", fmt.Errorf("no migrations to squash up to version %d", upTo)
+	}
+	squashedVersions := make([]uint, 0, len(squashed))
+	for _, m := range squashed {
+		squashedVersions = append(squashedVersions, m.Version)
+	}
+
+	baselinePath := filepath.Join(spanner.MigrationsDirs(migrationsDir)[0], fmt.Sprintf("%0*d_baseline.sql", versionWidth(squashed[len(squashed)-1].FileName), upTo))
+	if _, err := os.Stat(baselinePath); !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("baseline %s already exists", baselinePath)
+	}
+
+	lock, err := client.GetMigrationLock(ctx, options.LockTableName, options.LockIdentifier)
+	defer lock.Release()
+	if err != nil {
+		return "", err
+	}
+	if !lock.Success {
+		return "", fmt.Errorf("lock taken by another process %s which expires %v", lock.LockIdentifier, lock.Expiry)
+	}
+
+	if err := client.EnsureMigrationTable(ctx, options.VersionTableName, options.LockTableName); err != nil {
+		return "", err
+	}
+	if _, err := client.ExecuteMigrations(ctx, squashed, -1, options.VersionTableName, options.PartitionedDMLConcurrency, options.ProtoDescriptors, false); err != nil {
+		return "", err
+	}
+
+	ddl, err := client.LoadBaselineDDL(ctx, options.VersionTableName, options.LockTableName)
+	if err != nil {
+		return "", err
+	}
+	datas, err := client.LoadStaticDatas(ctx, options.StaticDataTables, options.StaticDataOrderBy)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- @wrench.StatementKind=%s\n", spanner.StatementKindBaseline)
+	fmt.Fprintf(&b, "-- @wrench.Squashed=%s\n", spanner.FormatVersionRanges(squashedVersions))
+	fmt.Fprintf(&b, "-- Baseline of migrations %s to %s, squashed by wrench migrate squash.\n", squashed[0].FileName, squashed[len(squashed)-1].FileName)
+	for _, statement := range ddl {
+		fmt.Fprintf(&b, "\n%s;\n", statement)
+	}
+	for _, data := range datas {
+		if len(data.Statements) > 0 {
+			fmt.Fprintf(&b, "\n%s\n", strings.Join(data.Statements, "\n"))
+		}
+	}
+
+	if err := os.WriteFile(baselinePath, []byte(b.String()), 0o644); err != nil {
+		return "", err
+	}
+
+	for _, m := range squashed {
+		// files in subdirectories keep their relative path in the archive
+		if err := os.MkdirAll(filepath.Join(archiveDir, filepath.FromSlash(path.Dir(m.FileName))), 0o755); err != nil {
+			return "", err
+		}
+		for _, fileName := range []string{m.FileName, spanner.AssertFileName(m.FileName)} {
+			f := spanner.MigrationFile{Dir: m.Dir, FileName: fileName}
+			err := os.Rename(f.Path(), filepath.Join(archiveDir, filepath.FromSlash(fileName)))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+		}
+	}
+
+	if err := updateSum(migrationsDir); err != nil {
+		return "", err
+	}
+
+	return baselinePath, nil
+}
+
+// versionWidth returns the number of digits in the version of a migration file name, to keep the zero prefix length.
+func versionWidth(fileName string) int {
+	fileName = path.Base(fileName)
+	if i := strings.IndexFunc(fileName, func(r rune) bool { return !unicode.IsDigit(r) }); i > 0 {
+		return i
+	}
+	return len(fileName)
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/roryq/wrench/pkg/spanner"
+)
+
+// WriteSum writes the wrench.sum manifest of the migration files in the migrations directory. Returns the path of the
+// sum file.
+func WriteSum(migrationsDir string) (string, error) {
+	sum, err := spanner.ComputeSum(migrationsDir)
+	if err != nil {
+		return "", err
+	}
+	if err := sum.Write(migrationsDir); err != nil {
+		return "", err
+	}
+	return filepath.Join(spanner.MigrationsDirs(migrationsDir)[0], spanner.SumFileName), nil
+}
+
+// ValidateSum returns an error if migration files were added, edited or deleted without updating the wrench.sum
+// manifest, or if a version is used by more than one migration file.
+func ValidateSum(migrationsDir string) error {
+	recorded, err := spanner.ReadSum(migrationsDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s not found in %s. Run migrate sum to create it", spanner.SumFileName, spanner.MigrationsDirs(migrationsDir)[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	sum, err := spanner.ComputeSum(migrationsDir)
+	if err != nil {
+		return err
+	}
+	if err := sum.Verify(recorded); err != nil {
+		return fmt.Errorf("%w\nRun migrate sum to update %s", err, spanner.SumFileName)
+	}
+
+	return nil
+}
+
+// updateSum rewrites the wrench.sum manifest if the migrations directory has one.
+func updateSum(migrationsDir string) error {
+	if _, err := os.Stat(filepath.Join(spanner.MigrationsDirs(migrationsDir)[0], spanner.SumFileName)); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	_, err := WriteSum(migrationsDir)
+	return err
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TemplateRepeatable is the name of the built-in template for repeatable migrations.
+const TemplateRepeatable = "repeatable"
+
+// builtinTemplates are the migration templates available in every project. The example statements are commented out so
+// that an unedited migration does nothing.
+var builtinTemplates = map[string]string{
+	"ddl": `-- @wrench.StatementKind=DDL
+--
+-- CREATE TABLE Singers (
+--   SingerID STRING(36) NOT NULL,
+--   FirstName STRING(1024),
+-- ) PRIMARY KEY(SingerID);
+--
+-- CREATE INDEX SingersByFirstName ON Singers(FirstName);
+`,
+	"dml": `-- @wrench.StatementKind=DML
+--
+-- Statements are applied in a single transaction.
+-- INSERT INTO Singers (SingerID, FirstName) VALUES ('1', 'Marc');
+-- UPDATE Singers SET FirstName = 'Marc' WHERE SingerID = '1';
+`,
+	"partitioned": `-- @wrench.StatementKind=PartitionedDML
+--
+-- Each statement is applied with the Partitioned DML API, so it must be idempotent and cannot be an INSERT.
+-- UPDATE Singers SET FirstName = UPPER(FirstName) WHERE FirstName IS NOT NULL;
+-- DELETE FROM Singers WHERE FirstName IS NULL;
+`,
+	"convergent": `-- @wrench.StatementKind=ConvergentDML
+-- @wrench.Concurrency=4
+--
+-- Each statement is applied repeatedly in its own transaction until no rows are affected, so limit the rows of each
+-- execution.
+-- DELETE FROM Singers WHERE SingerID IN (SELECT SingerID FROM Singers WHERE FirstName IS NULL LIMIT 1000);
+`,
+	TemplateRepeatable: `-- Repeatable migrations are applied whenever their contents change, so they must be idempotent.
+--
+-- CREATE OR REPLACE VIEW SingerNames SQL SECURITY INVOKER AS
+-- SELECT Singers.SingerID, Singers.FirstName FROM Singers;
+`,
+}
+
+// MigrationTemplate returns the contents of the named migration template. A project template, name.sql in
+// templatesDir, takes precedence over the built-in templates: ddl, dml, partitioned, convergent and repeatable.
+func MigrationTemplate(templatesDir, name string) (string, error) {
+	if templatesDir != "" {
+		file, err := os.ReadFile(filepath.Join(templatesDir, name+".sql"))
+		if err == nil {
+			return string(file), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	if template, ok := builtinTemplates[name]; ok {
+		return template, nil
+	}
+
+	names := slices.Collect(maps.Keys(builtinTemplates))
+	if templatesDir != "" {
+		project, _ := filepath.Glob(filepath.Join(templatesDir, "*.sql"))
+		for _, p := range project {
+			names = append(names, strings.TrimSuffix(filepath.Base(p), ".sql"))
+		}
+	}
+	slices.Sort(names)
+
+	return "", fmt.Errorf("unknown migration template %q, must be one of %s", name, strings.Join(slices.Compact(names), ", "))
+}
@@ -0,0 +1,132 @@
+package spanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/api/iterator"
+)
+
+// maxAssertionRows is the maximum number of offending rows included in an assertion error.
+const maxAssertionRows = 10
+
+// runAssertions runs the assertion queries of the migration. An assertion fails if it returns any rows, unless it
+// returns a single boolean that is true.
+func (c *Client) runAssertions(ctx context.Context, m *Migration) error {
+	for _, sql := range m.Assertions {
+		if err := c.runAssertion(ctx, sql); err != nil {
+			return &Error{
+				Code: ErrorCodeMigrationAssertion,
+				err:  fmt.Errorf("migration %s: assertion failed: %s\n%w", m.FileName, sql, err),
+			}
+		}
+	}
+
+	return nil
+}
+
+// assertMigrations runs the assertions of the versioned migrations. If an assertion fails the failure is recorded
+// against the dirty history rows and their operation name is cleared, so that ResumeMigrations does not mark the
+// versions clean once the operation that applied their DDL has succeeded.
+func (c *Client) assertMigrations(ctx context.Context, tableName string, migrations []*Migration) error {
+	for _, m := range migrations {
+		err := c.runAssertions(ctx, m)
+		if err == nil {
+			continue
+		}
+
+		mutations := make([]*spanner.Mutation, 0, len(migrations))
+		for _, m := range migrations {
+			mutations = append(mutations, spanner.Update(tableName+historyStr,
+				[]string{"Version", "OperationName", "FailureMessage"},
+				[]interface{}{int64(m.Version), spanner.NullString{}, err.Error()}))
+		}
+		if _, applyErr := c.spannerClient.Apply(ctx, mutations); applyErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to record the assertion failure of migration %s: %v\n", m.FileName, applyErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) runAssertion(ctx context.Context, sql string) error {
+	var rows []*spanner.Row
+	err := c.retry(ctx, func(ctx context.Context) error {
+		rows = nil
+		iter := c.spannerClient.Single().Query(ctx, spanner.NewStatement(sql))
+		defer iter.Stop()
+
+		for len(rows) <= maxAssertionRows {
+			row, err := iter.Next()
+			if errors.Is(err, iterator.Done) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return checkAssertionRows(rows)
+}
+
+// checkAssertionRows returns an error listing the rows, unless there are no rows or a single true boolean.
+func checkAssertionRows(rows []*spanner.Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if len(rows) == 1 && rows[0].Size() == 1 {
+		var v spanner.GenericColumnValue
+		if err := rows[0].Column(0, &v); err != nil {
+			return err
+		}
+		if v.Type.GetCode() == spannerpb.TypeCode_BOOL {
+			var b spanner.NullBool
+			if err := v.Decode(&b); err != nil {
+				return err
+			}
+			if b.Valid && b.Bool {
+				return nil
+			}
+			return fmt.Errorf("returned %v", b)
+		}
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		if i == maxAssertionRows {
+			sb.WriteString("...\n")
+			break
+		}
+		sb.WriteString(formatAssertionRow(row))
+		sb.WriteString("\n")
+	}
+
+	return fmt.Errorf("returned rows:\n%s", sb.String())
+}
+
+func formatAssertionRow(row *spanner.Row) string {
+	columns := make([]string, row.Size())
+	for i, name := range row.ColumnNames() {
+		var v spanner.GenericColumnValue
+		if err := row.Column(i, &v); err != nil {
+			columns[i] = fmt.Sprintf("%s=<%v>", name, err)
+			continue
+		}
+		columns[i] = fmt.Sprintf("%s=%v", name, v.Value.AsInterface())
+	}
+	return strings.Join(columns, ", ")
+}
@@ -0,0 +1,53 @@
+package spanner
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkAssertionRows(t *testing.T) {
+	newRow := func(t *testing.T, names []string, values ...interface{}) *spanner.Row {
+		row, err := spanner.NewRow(names, values)
+		require.NoError(t, err)
+		return row
+	}
+
+	t.Run("NoRows", func(t *testing.T) {
+		assert.NoError(t, checkAssertionRows(nil))
+	})
+
+	t.Run("True", func(t *testing.T) {
+		assert.NoError(t, checkAssertionRows([]*spanner.Row{newRow(t, []string{"ok"}, true)}))
+	})
+
+	t.Run("False", func(t *testing.T) {
+		err := checkAssertionRows([]*spanner.Row{newRow(t, []string{"ok"}, false)})
+		assert.ErrorContains(t, err, "false")
+	})
+
+	t.Run("NullBool", func(t *testing.T) {
+		err := checkAssertionRows([]*spanner.Row{newRow(t, []string{"ok"}, spanner.NullBool{})})
+		assert.Error(t, err)
+	})
+
+	t.Run("OffendingRows", func(t *testing.T) {
+		err := checkAssertionRows([]*spanner.Row{
+			newRow(t, []string{"SingerID", "FirstName"}, "1", spanner.NullString{}),
+			newRow(t, []string{"SingerID", "FirstName"}, "2", "Bar"),
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "SingerID=1, FirstName=<nil>")
+		assert.Contains(t, err.Error(), "SingerID=2, FirstName=Bar")
+	})
+
+	t.Run("TruncatedRows", func(t *testing.T) {
+		var rows []*spanner.Row
+		for range maxAssertionRows + 1 {
+			rows = append(rows, newRow(t, []string{"SingerID"}, "1"))
+		}
+		assert.ErrorContains(t, checkAssertionRows(rows), "...")
+	})
+}
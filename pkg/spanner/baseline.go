@@ -0,0 +1,207 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+// createTableNameRegex matches the name of the table created by a CREATE TABLE statement.
+var createTableNameRegex = regexp.MustCompile("(?is)^\\s*CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?`?([\\w.]+)`?")
+
+// LoadBaselineDDL returns the DDL statements of the database without the tables created by wrench to track migrations,
+// for the baseline written by migrate squash.
+func (c *Client) LoadBaselineDDL(ctx context.Context, versionTableName, lockTableName string) ([]string, error) {
+	req := &databasepb.GetDatabaseDdlRequest{Database: c.config.URL()}
+
+	res, err := c.spannerAdminClient.GetDatabaseDdl(ctx, req)
+	if err != nil {
+		return nil, &Error{
+			Code: ErrorCodeLoadSchema,
+			err:  err,
+		}
+	}
+
+	return excludeTables(res.Statements, baselineExcludedTables(versionTableName, lockTableName)), nil
+}
+
+// baselineExcludedTables returns the tracking tables that are created by wrench rather than the baseline. The lock
+// table named after the version table is excluded as well as the shared lock table, as it was created by earlier
+// versions of wrench.
+func baselineExcludedTables(versionTableName, lockTableName string) []string {
+	return []string{
+		versionTableName,
+		versionTableName + historyStr,
+		RepeatableHistoryTableName(versionTableName),
+		SeedHistoryTableName(versionTableName),
+		versionTableName + lockStr,
+		lockTableName,
+		upgradeIndicator,
+	}
+}
+
+// excludeTables removes the CREATE TABLE statements of the given tables.
+func excludeTables(statements []string, tables []string) []string {
+	return slices.DeleteFunc(slices.Clone(statements), func(s string) bool {
+		matches := createTableNameRegex.FindStringSubmatch(s)
+		return matches != nil && slices.Contains(tables, matches[1])
+	})
+}
+
+// splitBaselineStatements splits the statements of a baseline migration into the DDL and the DML statements.
+func splitBaselineStatements(statements []string) (ddl, dml []string) {
+	for _, s := range statements {
+		if getStatementKind(s) == StatementKindDDL {
+			ddl = append(ddl, s)
+		} else {
+			dml = append(dml, s)
+		}
+	}
+	return ddl, dml
+}
+
+// applyBaseline applies the DDL statements of a baseline migration in a single operation, then the DML statements that
+// insert its static data.
+func (c *Client) applyBaseline(ctx context.Context, tableName string, m *Migration, protoDescriptors []byte) (int64, error) {
+	ddl, dml := splitBaselineStatements(m.Statements)
+
+	if len(ddl) > 0 {
+		schema := *m
+		schema.Statements = ddl
+		if err := c.applyMigrationDDL(ctx, tableName, []*Migration{&schema}, protoDescriptors); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(dml) == 0 {
+		return 0, nil
+	}
+
+	data := *m
+	data.Statements = dml
+	return c.applyMigrationDML(ctx, &data, StatementKindDML, 0)
+}
+
+// recordBaseline records a pending baseline migration as applied, without executing it, if the database has applied
+// the versions squashed into the baseline, so the database already has its schema. It returns an error if the
+// database has applied only some of the squashed versions, as the others were archived and would never be applied.
+// The version table is not changed.
+func (c *Client) recordBaseline(ctx context.Context, migrations Migrations, history []MigrationHistoryRecord, tableName string) ([]MigrationHistoryRecord, error) {
+	for _, m := range migrations {
+		if m.IsRepeatable || m.Kind != StatementKindBaseline {
+			continue
+		}
+
+		record, err := squashedIntoBaseline(m, history)
+		if err != nil {
+			return nil, &Error{
+				Code: ErrorCodeMigrationOrder,
+				err:  err,
+			}
+		}
+		if !record {
+			continue
+		}
+
+		err = c.retry(ctx, func(ctx context.Context) error {
+			_, err := c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+				return c.upsertVersionHistory(ctx, tx, int64(m.Version), false, tableName+historyStr)
+			})
+			return err
+		})
+		if err != nil {
+			return nil, &Error{
+				Code: ErrorCodeSetMigrationVersion,
+				err:  err,
+			}
+		}
+
+		fmt.Fprintf(c.Output(), "%d/baseline (already applied)\n", m.Version)
+		history = append(history, MigrationHistoryRecord{Version: int64(m.Version)})
+	}
+
+	return history, nil
+}
+
+// squashedIntoBaseline returns true if the database has applied every version squashed into the baseline migration
+// but not the baseline itself, and an error if it has applied only some of them. Recording the baseline as applied
+// once any squashed version is applied would leave the schema of the missing versions out of the database for good,
+// as the baseline is never executed and the squashed migrations are archived. Baselines without the Squashed
+// directive are recorded once the database has applied a later version.
+func squashedIntoBaseline(m *Migration, history []MigrationHistoryRecord) (bool, error) {
+	applied := make(map[int64]bool, len(history))
+	for _, h := range history {
+		applied[h.Version] = true
+	}
+	if applied[int64(m.Version)] {
+		return false, nil
+	}
+
+	if len(m.Directives.Squashed) == 0 {
+		return slices.ContainsFunc(history, func(h MigrationHistoryRecord) bool { return h.Version > int64(m.Version) }), nil
+	}
+
+	var missing []uint
+	for _, v := range m.Directives.Squashed {
+		if !applied[int64(v)] {
+			missing = append(missing, v)
+		}
+	}
+	switch len(missing) {
+	case 0:
+		return true, nil
+	case len(m.Directives.Squashed):
+		// a new database, the baseline is executed
+		return false, nil
+	default:
+		return false, fmt.Errorf("baseline %s cannot be recorded as applied as the database has not applied the squashed versions %s, apply them from the archive first", m.FileName, FormatVersionRanges(missing))
+	}
+}
+
+// FormatVersionRanges formats the sorted versions as a comma separated list where consecutive versions are written
+// as a range, e.g. 1-3,5.
+func FormatVersionRanges(versions []uint) string {
+	var ranges []string
+	for i := 0; i < len(versions); {
+		j := i
+		for j+1 < len(versions) && versions[j+1] == versions[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.FormatUint(uint64(versions[i]), 10))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", versions[i], versions[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// parseVersionRanges parses the versions formatted by FormatVersionRanges.
+func parseVersionRanges(s string) ([]uint, error) {
+	var versions []uint
+	for _, r := range strings.Split(s, ",") {
+		fromStr, toStr, isRange := strings.Cut(r, "-")
+		if !isRange {
+			toStr = fromStr
+		}
+		from, err := strconv.ParseUint(fromStr, 10, 64)
+		if err != nil || from == 0 {
+			return nil, fmt.Errorf("invalid version: %s", r)
+		}
+		to, err := strconv.ParseUint(toStr, 10, 64)
+		if err != nil || to < from {
+			return nil, fmt.Errorf("invalid version range: %s", r)
+		}
+		for v := from; v <= to; v++ {
+			versions = append(versions, uint(v))
+		}
+	}
+	return versions, nil
+}
@@ -0,0 +1,158 @@
+package spanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_excludeTables(t *testing.T) {
+	statements := []string{
+		"CREATE TABLE SchemaMigrations (\n  Version INT64 NOT NULL,\n  Dirty BOOL NOT NULL,\n) PRIMARY KEY(Version)",
+		"CREATE TABLE SchemaMigrationsHistory (\n  Version INT64 NOT NULL,\n) PRIMARY KEY(Version)",
+		"CREATE TABLE Singers (\n  SingerID STRING(36) NOT NULL,\n) PRIMARY KEY(SingerID)",
+		"CREATE INDEX SingersByName ON Singers(SingerID)",
+		"CREATE TABLE `SchemaMigrationsLock` (\n  Id STRING(36) NOT NULL,\n) PRIMARY KEY(Id)",
+	}
+
+	got := excludeTables(statements, []string{"SchemaMigrations", "SchemaMigrationsHistory", "SchemaMigrationsLock"})
+
+	assert.Equal(t, []string{statements[2], statements[3]}, got)
+	assert.Len(t, statements, 5, "statements should not be modified")
+}
+
+func Test_baselineExcludedTables(t *testing.T) {
+	statements := []string{
+		"CREATE TABLE billing_SchemaMigrations (\n  Version INT64 NOT NULL,\n  Dirty BOOL NOT NULL,\n) PRIMARY KEY(Version)",
+		"CREATE TABLE billing_SchemaMigrationsHistory (\n  Version INT64 NOT NULL,\n) PRIMARY KEY(Version)",
+		"CREATE TABLE billing_SchemaMigrationsLock (\n  ID INT64,\n) PRIMARY KEY(ID)",
+		"CREATE TABLE MigrationsLock (\n  ID INT64,\n) PRIMARY KEY(ID)",
+		"CREATE TABLE Invoices (\n  InvoiceID STRING(36) NOT NULL,\n) PRIMARY KEY(InvoiceID)",
+	}
+
+	got := excludeTables(statements, baselineExcludedTables("billing_SchemaMigrations", "MigrationsLock"))
+
+	assert.Equal(t, []string{statements[4]}, got)
+}
+
+func Test_splitBaselineStatements(t *testing.T) {
+	ddl, dml := splitBaselineStatements([]string{
+		"CREATE TABLE Singers (\n  SingerID STRING(36) NOT NULL,\n) PRIMARY KEY(SingerID)",
+		"CREATE INDEX SingersByName ON Singers(SingerID)",
+		"INSERT INTO Singers(SingerID) VALUES('1')",
+		"INSERT INTO Singers(SingerID) VALUES('2')",
+	})
+
+	assert.Len(t, ddl, 2)
+	assert.Equal(t, []string{"INSERT INTO Singers(SingerID) VALUES('1')", "INSERT INTO Singers(SingerID) VALUES('2')"}, dml)
+}
+
+func TestLoadMigrationsBaseline(t *testing.T) {
+	baseline := "-- @wrench.StatementKind=Baseline\n" +
+		"CREATE TABLE Singers (\n  SingerID STRING(36) NOT NULL,\n) PRIMARY KEY(SingerID);\n" +
+		"INSERT INTO Singers(SingerID) VALUES('1');\n"
+
+	t.Run("mixed DDL and DML", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "0900_baseline.sql"), []byte(baseline), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "0910_add_index.sql"), []byte("CREATE INDEX SingersByName ON Singers(SingerID);"), 0o644))
+
+		ms, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+		require.NoError(t, err)
+		require.Len(t, ms, 2)
+		assert.Equal(t, StatementKindBaseline, ms[0].Kind)
+		assert.Len(t, ms[0].Statements, 2)
+		assert.Equal(t, StatementKindDDL, ms[1].Kind)
+	})
+
+	t.Run("not the lowest version", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_first.sql"), []byte("CREATE INDEX SingersByName ON Singers(SingerID);"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "0900_baseline.sql"), []byte(baseline), 0o644))
+
+		_, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+		assert.ErrorContains(t, err, "baseline migration 0900_baseline.sql must have the lowest version")
+	})
+
+	t.Run("repeatable", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "R__baseline.sql"), []byte(baseline), 0o644))
+
+		_, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+		assert.ErrorContains(t, err, "a baseline must be a versioned migration")
+	})
+}
+
+func Test_hasOutOfOrderMigrationsBaseline(t *testing.T) {
+	migrations := Migrations{{Version: 900, Kind: StatementKindBaseline}, {Version: 910}}
+
+	// versions squashed into the baseline are ignored
+	assert.False(t, hasOutOfOrderMigrations(migrations, map[int64]bool{1: true, 2: true, 900: true}))
+	assert.True(t, hasOutOfOrderMigrations(migrations, map[int64]bool{1: true, 910: true}))
+}
+
+func Test_squashedIntoBaseline(t *testing.T) {
+	baseline := &Migration{Version: 900, FileName: "0900_baseline.sql", Kind: StatementKindBaseline}
+	baseline.Directives.Squashed = []uint{1, 2, 3, 890}
+
+	historyOf := func(versions ...int64) []MigrationHistoryRecord {
+		var history []MigrationHistoryRecord
+		for _, v := range versions {
+			history = append(history, MigrationHistoryRecord{Version: v})
+		}
+		return history
+	}
+
+	tests := []struct {
+		name    string
+		history []MigrationHistoryRecord
+		want    bool
+		wantErr string
+	}{
+		{name: "new database", history: nil, want: false},
+		{name: "every squashed version applied", history: historyOf(1, 2, 3, 890), want: true},
+		{name: "baseline already recorded", history: historyOf(1, 2, 3, 890, 900), want: false},
+		{name: "stopped before the last squashed version", history: historyOf(1, 2), wantErr: "has not applied the squashed versions 3,890"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := squashedIntoBaseline(baseline, tt.history)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// without the Squashed directive the baseline is recorded once a later version is applied
+	legacy := &Migration{Version: 900, FileName: "0900_baseline.sql", Kind: StatementKindBaseline}
+	got, err := squashedIntoBaseline(legacy, historyOf(1, 2))
+	require.NoError(t, err)
+	assert.False(t, got)
+	got, err = squashedIntoBaseline(legacy, historyOf(1, 2, 910))
+	require.NoError(t, err)
+	assert.True(t, got)
+}
+
+func TestFormatVersionRanges(t *testing.T) {
+	versions := []uint{1, 2, 3, 5, 10, 11, 20}
+	assert.Equal(t, "1-3,5,10-11,20", FormatVersionRanges(versions))
+
+	parsed, err := parseVersionRanges("1-3,5,10-11,20")
+	require.NoError(t, err)
+	assert.Equal(t, versions, parsed)
+
+	for _, s := range []string{"", "0", "3-1", "a", "1-"} {
+		_, err := parseVersionRanges(s)
+		assert.Error(t, err, s)
+	}
+
+	directives, err := parseMigrationDirectives("-- @wrench.StatementKind=Baseline\n-- @wrench.Squashed=1-3,5\nCREATE TABLE T (ID INT64) PRIMARY KEY(ID);")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3, 5}, directives.Squashed)
+}
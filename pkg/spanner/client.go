@@ -24,6 +24,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"maps"
 	"os"
 	"slices"
@@ -33,13 +34,16 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/google/uuid"
 	"github.com/googleapis/gax-go/v2"
 	"github.com/sourcegraph/conc/pool"
 
 	"github.com/roryq/wrench/pkg/spannerz"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"cloud.google.com/go/spanner"
 	admin "cloud.google.com/go/spanner/admin/database/apiv1"
 	databasepb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
@@ -55,6 +59,7 @@ const (
 	upgradeIndicator                   = "wrench_upgrade_indicator"
 	historyStr                         = "History"
 	repeatableHistoryStr               = "RepeatableHistory"
+	seedHistoryStr                     = "SeedHistory"
 	lockStr                            = "Lock"
 	FirstRun                           = UpgradeStatus("FirstRun")
 	ExistingMigrationsNoUpgrade        = UpgradeStatus("NoUpgrade")
@@ -107,6 +112,9 @@ type Client struct {
 	config             *Config
 	spannerClient      *spanner.Client
 	spannerAdminClient *admin.DatabaseAdminClient
+
+	// stopping is set to stop executing migrations once the current migration has completed.
+	stopping atomic.Bool
 }
 
 type MigrationHistoryRecord struct {
@@ -114,6 +122,14 @@ type MigrationHistoryRecord struct {
 	Dirty    bool      `spanner:"Dirty"`
 	Created  time.Time `spanner:"Created"`
 	Modified time.Time `spanner:"Modified"`
+	// OperationName is the long-running operation submitted for a DDL migration.
+	OperationName spanner.NullString `spanner:"OperationName"`
+	// CommittedStatements is the number of statements committed by the operation before it failed.
+	CommittedStatements spanner.NullInt64 `spanner:"CommittedStatements"`
+	// FailureMessage is the error returned by the failed operation.
+	FailureMessage spanner.NullString `spanner:"FailureMessage"`
+	// SkipReason is set if the migration was recorded without being executed.
+	SkipReason spanner.NullString `spanner:"SkipReason"`
 }
 
 type RepeatableMigrationHistoryRecord struct {
@@ -126,6 +142,12 @@ func RepeatableHistoryTableName(versionTableName string) string {
 	return versionTableName + repeatableHistoryStr
 }
 
+// SeedHistoryTableName is the table that tracks the checksums of applied seeds, which has the same schema as the
+// repeatable history table.
+func SeedHistoryTableName(versionTableName string) string {
+	return versionTableName + seedHistoryStr
+}
+
 func NewClient(ctx context.Context, config *Config) (*Client, error) {
 	opts := make([]option.ClientOption, 0)
 	if config.CredentialsFile != "" {
@@ -648,31 +670,304 @@ func (c *Client) ApplyDDLFile(ctx context.Context, ddl []byte, placeholderOption
 }
 
 func (c *Client) ApplyDDL(ctx context.Context, statements []string, protoDescriptors []byte) error {
+	op, err := c.submitDDL(ctx, statements, protoDescriptors)
+	if err != nil {
+		return err
+	}
+
+	return c.waitDDL(ctx, op)
+}
+
+func (c *Client) submitDDL(ctx context.Context, statements []string, protoDescriptors []byte) (*admin.UpdateDatabaseDdlOperation, error) {
+	// a request that timed out may have created the operation, so each attempt submits the same operation ID and an
+	// attempt that finds the operation already exists attaches to it rather than submitting the statements again
+	operationID := ddlOperationID()
 	req := &databasepb.UpdateDatabaseDdlRequest{
 		Database:         c.config.URL(),
 		Statements:       statements,
 		ProtoDescriptors: protoDescriptors,
+		OperationId:      operationID,
 	}
 
-	op, err := c.spannerAdminClient.UpdateDatabaseDdl(ctx, req)
+	var op *admin.UpdateDatabaseDdlOperation
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		op, err = c.spannerAdminClient.UpdateDatabaseDdl(ctx, req)
+		if status.Code(err) == codes.AlreadyExists {
+			op = c.spannerAdminClient.UpdateDatabaseDdlOperation(c.config.URL() + "/operations/" + operationID)
+			return nil
+		}
+		return err
+	})
 	if err != nil {
-		return &Error{
+		return nil, &Error{
 			Code: ErrorCodeUpdateDDL,
 			err:  err,
 		}
 	}
 
-	err = op.Wait(ctx)
+	return op, nil
+}
+
+// ddlOperationID returns a unique operation ID for a DDL request, which must start with a letter and only contain
+// lowercase letters, digits and underscores.
+func ddlOperationID() string {
+	return "wrench_" + strings.ReplaceAll(uuid.NewString(), "-", "_")
+}
+
+// waitDDL polls the operation until it is done, emitting the progress of each statement. If the context is cancelled
+// the operation is cancelled too, rather than left running in the background.
+func (c *Client) waitDDL(ctx context.Context, op *admin.UpdateDatabaseDdlOperation) (err error) {
+	defer func() {
+		if err != nil && ctx.Err() != nil && !op.Done() {
+			c.cancelDDL(context.WithoutCancel(ctx), op)
+		}
+	}()
+
+	start := time.Now()
+	backoff := gax.Backoff{
+		Initial:    100 * time.Millisecond,
+		Max:        progressInterval,
+		Multiplier: 2,
+	}
+	for {
+		// retry polling, but not the error of a failed operation
+		var opErr error
+		err := c.retry(ctx, func(ctx context.Context) error {
+			err := op.Poll(ctx)
+			if op.Done() {
+				opErr = err
+				return nil
+			}
+			return err
+		})
+		err = cmp.Or(err, opErr)
+		c.emitDDLProgress(op, start)
+		if err != nil {
+			return &Error{
+				Code: ErrorCodeWaitOperation,
+				err:  err,
+			}
+		}
+		if op.Done() {
+			return nil
+		}
+
+		if err := gax.Sleep(ctx, backoff.Pause()); err != nil {
+			return &Error{
+				Code: ErrorCodeWaitOperation,
+				err:  err,
+			}
+		}
+	}
+}
+
+// cancelDDL cancels the operation. Statements already committed by the operation are not rolled back.
+func (c *Client) cancelDDL(ctx context.Context, op *admin.UpdateDatabaseDdlOperation) {
+	fmt.Fprintf(os.Stderr, "cancelling operation %s\n", op.Name())
+	err := c.spannerAdminClient.CancelOperation(ctx, &longrunningpb.CancelOperationRequest{Name: op.Name()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to cancel operation %s: %v\n", op.Name(), err)
+	}
+}
+
+// applyMigrationDDL submits the DDL of the migrations in a single operation and records the operation name against the
+// dirty history rows before waiting, so that the operation can be resumed if wrench is interrupted.
+func (c *Client) applyMigrationDDL(ctx context.Context, tableName string, migrations []*Migration, protoDescriptors []byte) error {
+	return withMigrationTimeout(ctx, migrations, func(ctx context.Context) error {
+		return c.applyMigrationDDLOnce(ctx, tableName, migrations, protoDescriptors)
+	})
+}
+
+func (c *Client) applyMigrationDDLOnce(ctx context.Context, tableName string, migrations []*Migration, protoDescriptors []byte) error {
+	var versions []uint
+	var statements []string
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+		statements = append(statements, m.Statements...)
+	}
+
+	op, err := c.submitDDL(ctx, statements, protoDescriptors)
+	if err != nil {
+		return err
+	}
+
+	if err := c.setMigrationOperation(ctx, tableName, versions, op.Name()); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record operation %s for versions %v: %v\n", op.Name(), versions, err)
+	}
+
+	if err := c.waitDDL(ctx, op); err != nil {
+		c.recordOperationFailure(ctx, tableName, versions, op, err)
+		return err
+	}
+
+	return nil
+}
+
+// recordOperationFailure records how many statements the failed operation committed against the dirty history rows.
+// Spanner commits each DDL statement in turn, so the statements before the failing statement remain applied.
+func (c *Client) recordOperationFailure(ctx context.Context, tableName string, versions []uint, op *admin.UpdateDatabaseDdlOperation, opErr error) {
+	// the operation may still be running if only waiting for it failed
+	if !op.Done() {
+		return
+	}
+
+	var committed int64
+	if meta, err := op.Metadata(); err == nil && meta != nil {
+		committed = int64(len(meta.CommitTimestamps))
+	}
+
+	m := make([]*spanner.Mutation, 0, len(versions))
+	for _, v := range versions {
+		m = append(m, spanner.Update(tableName+historyStr,
+			[]string{"Version", "CommittedStatements", "FailureMessage"},
+			[]interface{}{int64(v), committed, opErr.Error()}))
+	}
+
+	if _, err := c.spannerClient.Apply(ctx, m); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record failure of operation %s for versions %v: %v\n", op.Name(), versions, err)
+	}
+}
+
+func (c *Client) setMigrationOperation(ctx context.Context, tableName string, versions []uint, operationName string) error {
+	m := make([]*spanner.Mutation, 0, len(versions))
+	for _, v := range versions {
+		m = append(m, spanner.Update(tableName+historyStr,
+			[]string{"Version", "OperationName"},
+			[]interface{}{int64(v), operationName}))
+	}
+
+	return c.retry(ctx, func(ctx context.Context) error {
+		_, err := c.spannerClient.Apply(ctx, m)
+		return err
+	})
+}
+
+// ResumeMigrations reattaches to the DDL operations recorded against dirty versions in the history table, then marks
+// the versions clean once the operation has completed successfully. If the operation failed the error is returned and
+// the versions are left dirty.
+// It returns the versions that were resumed, or an ErrorCodeMigrationVersionDirty error if a dirty version has no
+// operation to resume.
+func (c *Client) ResumeMigrations(ctx context.Context, tableName string) ([]uint, error) {
+	dirty, err := c.GetDirtyMigrations(ctx, tableName)
+	if err != nil {
+		return nil, &Error{
+			Code: ErrorCodeResumeMigration,
+			err:  err,
+		}
+	}
+
+	var operations []string
+	versionsByOperation := make(map[string][]uint)
+	for _, record := range dirty {
+		if !record.OperationName.Valid {
+			return nil, &Error{
+				Code: ErrorCodeMigrationVersionDirty,
+				err:  fmt.Errorf("database version: %d is dirty, please fix it.", record.Version),
+			}
+		}
+
+		name := record.OperationName.StringVal
+		if _, ok := versionsByOperation[name]; !ok {
+			operations = append(operations, name)
+		}
+		versionsByOperation[name] = append(versionsByOperation[name], uint(record.Version))
+	}
+
+	var resumed []uint
+	for _, name := range operations {
+		versions := versionsByOperation[name]
+		fmt.Fprintf(c.Output(), "Waiting for operation %s of versions %v\n", name, versions)
+
+		op := c.spannerAdminClient.UpdateDatabaseDdlOperation(name)
+		if err := c.waitDDL(ctx, op); err != nil {
+			c.recordOperationFailure(ctx, tableName, versions, op, err)
+			return resumed, err
+		}
+
+		for _, v := range versions {
+			if err := c.setSchemaMigrationVersion(ctx, v, false, tableName); err != nil {
+				return resumed, err
+			}
+			fmt.Fprintf(c.Output(), "%d/up (resumed)\n", v)
+			resumed = append(resumed, v)
+		}
+	}
+
+	return resumed, nil
+}
+
+// MarkMigrationsApplied marks the dirty versions as applied without executing them, for example once the statements
+// that were not applied by a failed operation have been moved to a new migration.
+func (c *Client) MarkMigrationsApplied(ctx context.Context, tableName string, versions []uint) error {
+	for _, v := range versions {
+		if err := c.setSchemaMigrationVersion(ctx, v, false, tableName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StopAfterCurrentMigration stops executing migrations once the migration currently executing has completed. It is safe
+// to call concurrently, e.g. from a signal handler.
+func (c *Client) StopAfterCurrentMigration() {
+	c.stopping.Store(true)
+}
+
+// Stopping returns true if StopAfterCurrentMigration has been called.
+func (c *Client) Stopping() bool {
+	return c.stopping.Load()
+}
+
+// Output returns the writer that the result of each migration is written to.
+func (c *Client) Output() io.Writer {
+	if c.config.Output != nil {
+		return c.config.Output
+	}
+	return os.Stdout
+}
+
+// skipMigration records the migration as applied without executing it.
+func (c *Client) skipMigration(ctx context.Context, m *Migration, tableName string) error {
+	err := c.retry(ctx, func(ctx context.Context) error {
+		_, err := c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+			if err := tx.BufferWrite(setSchemaVersionMutations(tableName, m.Version, false)); err != nil {
+				return err
+			}
+			if err := c.upsertVersionHistory(ctx, tx, int64(m.Version), false, tableName+historyStr); err != nil {
+				return err
+			}
+
+			return tx.BufferWrite([]*spanner.Mutation{
+				spanner.Update(tableName+historyStr,
+					[]string{"Version", "SkipReason"},
+					[]interface{}{int64(m.Version), m.SkipReason}),
+			})
+		})
+		return err
+	})
 	if err != nil {
 		return &Error{
-			Code: ErrorCodeWaitOperation,
+			Code: ErrorCodeSetMigrationVersion,
 			err:  err,
 		}
 	}
 
+	fmt.Fprintf(c.Output(), "%d/skipped (%s)\n", m.Version, m.SkipReason)
 	return nil
 }
 
+// GetDirtyMigrations returns the dirty history records ordered by version.
+func (c *Client) GetDirtyMigrations(ctx context.Context, tableName string) ([]MigrationHistoryRecord, error) {
+	sql := "SELECT * FROM " + tableName + historyStr + " WHERE Dirty = TRUE ORDER BY Version"
+	var dirty []MigrationHistoryRecord
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		dirty, err = spannerz.GetSQL[MigrationHistoryRecord](ctx, c.spannerClient.Single(), sql)
+		return err
+	})
+	return dirty, err
+}
+
 func (c *Client) ApplyDMLFile(ctx context.Context, dml []byte, partitioned bool, concurrency int, placeholderOptions PlaceholderOptions) (int64, error) {
 	statements, err := toStatements(dml)
 	if err != nil {
@@ -719,6 +1014,9 @@ func (c *Client) ApplyDML(ctx context.Context, statements []string) (int64, erro
 func (c *Client) ApplyPartitionedDML(ctx context.Context, statements []string, concurrency int) (int64, error) {
 	numAffectedRows := atomic.Int64{}
 
+	progress := c.startDMLProgress(len(statements))
+	defer progress.stop()
+
 	concurrency = cmp.Or(concurrency, 1)
 	p := pool.New().WithMaxGoroutines(concurrency).WithErrors()
 	for _, s := range statements {
@@ -731,6 +1029,7 @@ func (c *Client) ApplyPartitionedDML(ctx context.Context, statements []string, c
 			}
 
 			numAffectedRows.Add(num)
+			progress.statementDone(num)
 			return nil
 		})
 	}
@@ -836,16 +1135,11 @@ func (c *Client) GetMigrationHistory(ctx context.Context, versionTableName strin
 		}
 	}
 
-	history := make([]MigrationHistoryRecord, 0)
-	stmt := spanner.NewStatement("SELECT Version, Dirty, Created, Modified FROM " + versionTableName + historyStr)
-	err := c.spannerClient.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
-		version := MigrationHistoryRecord{}
-		if err := r.ToStruct(&version); err != nil {
-			return err
-		}
-		history = append(history, version)
-
-		return nil
+	// select all columns as history tables created by older versions may not have been upgraded yet
+	var history []MigrationHistoryRecord
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		history, err = spannerz.GetSQL[MigrationHistoryRecord](ctx, c.spannerClient.Single(), "SELECT * FROM "+versionTableName+historyStr)
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -859,16 +1153,19 @@ func (c *Client) GetRepeatableMigrationHistory(ctx context.Context, tableName st
 		return nil, nil
 	}
 
-	history := make([]RepeatableMigrationHistoryRecord, 0)
+	var history []RepeatableMigrationHistoryRecord
 	stmt := spanner.NewStatement("SELECT Name, Checksum, AppliedAt FROM " + tableName)
-	err := c.spannerClient.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
-		record := RepeatableMigrationHistoryRecord{}
-		if err := r.ToStruct(&record); err != nil {
-			return err
-		}
-		history = append(history, record)
+	err := c.retry(ctx, func(ctx context.Context) error {
+		history = make([]RepeatableMigrationHistoryRecord, 0)
+		return c.spannerClient.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+			record := RepeatableMigrationHistoryRecord{}
+			if err := r.ToStruct(&record); err != nil {
+				return err
+			}
+			history = append(history, record)
 
-		return nil
+			return nil
+		})
 	})
 	if err != nil {
 		return nil, err
@@ -877,6 +1174,20 @@ func (c *Client) GetRepeatableMigrationHistory(ctx context.Context, tableName st
 	return history, nil
 }
 
+// DeleteRepeatableMigrationHistory deletes the history of the named repeatable migrations, e.g. after their files were
+// deleted.
+func (c *Client) DeleteRepeatableMigrationHistory(ctx context.Context, tableName string, names []string) error {
+	mutations := make([]*spanner.Mutation, 0, len(names))
+	for _, name := range names {
+		mutations = append(mutations, spanner.Delete(tableName, spanner.Key{name}))
+	}
+
+	return c.retry(ctx, func(ctx context.Context) error {
+		_, err := c.spannerClient.Apply(ctx, mutations)
+		return err
+	})
+}
+
 type MigrationsOutput map[string]migrationInfo
 
 type migrationInfo struct {
@@ -919,9 +1230,17 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 	}
 
 	if dirty {
-		return nil, &Error{
-			Code: ErrorCodeMigrationVersionDirty,
-			err:  fmt.Errorf("database version: %d is dirty, please fix it.", version),
+		// the DDL operation of the dirty version may still be running, or have completed after wrench was interrupted
+		if _, err := c.ResumeMigrations(ctx, tableName); err != nil {
+			return nil, err
+		}
+
+		version, _, err = c.GetSchemaMigrationVersion(ctx, tableName)
+		if err != nil {
+			return nil, &Error{
+				Code: ErrorCodeExecuteMigrations,
+				err:  err,
+			}
 		}
 	}
 
@@ -933,11 +1252,23 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 		}
 	}
 
+	history, err = c.recordBaseline(ctx, migrations, history, tableName)
+	if err != nil {
+		return nil, err
+	}
+
 	applied := make(map[int64]bool)
 	for i := range history {
 		applied[history[i].Version] = true
 	}
 
+	if err := checkOutOfOrder(migrations, history, c.config.OutOfOrder); err != nil {
+		return nil, err
+	}
+	if err := checkRequires(migrations, history); err != nil {
+		return nil, err
+	}
+
 	var migrationsOutput MigrationsOutput = make(MigrationsOutput)
 	var count int
 
@@ -955,6 +1286,21 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 			continue
 		}
 
+		if c.Stopping() {
+			fmt.Fprintf(c.Output(), "stopped before %s\n", m.FileName)
+			return migrationsOutput, nil
+		}
+
+		if m.SkipReason != "" {
+			if err := c.skipMigration(ctx, m, tableName); err != nil {
+				return nil, &Error{
+					Code: ErrorCodeExecuteMigrations,
+					err:  err,
+				}
+			}
+			continue
+		}
+
 		if err := c.setSchemaMigrationVersion(ctx, m.Version, true, tableName); err != nil {
 			return nil, &Error{
 				Code: ErrorCodeExecuteMigrations,
@@ -962,17 +1308,20 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 			}
 		}
 
+		start := time.Now()
+		c.emit(Event{Kind: EventMigrationStarted, Migration: m.FileName})
+
 		statementKind := cmp.Or(m.Directives.StatementKind, m.Kind)
 		switch statementKind {
 		case StatementKindDDL:
-			if err := c.ApplyDDL(ctx, m.Statements, protoDescriptors); err != nil {
+			if err := c.applyMigrationDDL(ctx, tableName, []*Migration{m}, protoDescriptors); err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
 					err:  err,
 				}
 			}
 		case StatementKindDML:
-			rowsAffected, err := c.ApplyDML(ctx, m.Statements)
+			rowsAffected, err := c.applyMigrationDML(ctx, m, StatementKindDML, partitionedConcurrency)
 			if err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
@@ -984,7 +1333,7 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 				RowsAffected: rowsAffected,
 			}
 		case StatementKindPartitionedDML:
-			rowsAffected, err := c.ApplyPartitionedDML(ctx, m.Statements, partitionedConcurrency)
+			rowsAffected, err := c.applyMigrationDML(ctx, m, StatementKindPartitionedDML, partitionedConcurrency)
 			if err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
@@ -996,7 +1345,19 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 				RowsAffected: rowsAffected,
 			}
 		case StatementKindConvergentDML:
-			rowsAffected, err := convergentApply(ctx, c.ApplyDML, m.Statements, m.Directives.Concurrency)
+			rowsAffected, err := c.applyMigrationDML(ctx, m, StatementKindConvergentDML, partitionedConcurrency)
+			if err != nil {
+				return nil, &Error{
+					Code: ErrorCodeExecuteMigrations,
+					err:  err,
+				}
+			}
+
+			migrationsOutput[m.FileName] = migrationInfo{
+				RowsAffected: rowsAffected,
+			}
+		case StatementKindBaseline:
+			rowsAffected, err := c.applyBaseline(ctx, tableName, m, protoDescriptors)
 			if err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
@@ -1014,10 +1375,20 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 			}
 		}
 
+		if err := c.assertMigrations(ctx, tableName, []*Migration{m}); err != nil {
+			return nil, err
+		}
+
+		c.emit(Event{
+			Kind:         EventMigrationCompleted,
+			Migration:    m.FileName,
+			RowsAffected: migrationsOutput[m.FileName].RowsAffected,
+			Elapsed:      time.Since(start),
+		})
 		if m.Name != "" {
-			fmt.Printf("%d/up %s\n", m.Version, m.Name)
+			fmt.Fprintf(c.Output(), "%d/up %s\n", m.Version, m.Name)
 		} else {
-			fmt.Printf("%d/up\n", m.Version)
+			fmt.Fprintf(c.Output(), "%d/up\n", m.Version)
 		}
 
 		if err := c.setSchemaMigrationVersion(ctx, m.Version, false, tableName); err != nil {
@@ -1034,7 +1405,7 @@ func (c *Client) ExecuteMigrations(ctx context.Context, migrations Migrations, l
 	}
 
 	if count == 0 {
-		fmt.Println("no change")
+		fmt.Fprintln(c.Output(), "no change")
 	}
 
 	return migrationsOutput, nil
@@ -1068,22 +1439,39 @@ func (c *Client) ExecuteRepeatableMigrations(ctx context.Context, migrations Mig
 
 	migrationsOutput := make(MigrationsOutput)
 	for _, m := range migrations {
-		if appliedChecksum, ok := applied[m.Name]; ok && appliedChecksum == m.Checksum {
+		if appliedChecksum, ok := applied[m.Name]; ok && appliedChecksum == m.Checksum && !m.Directives.RunAlways {
 			continue
 		}
 
+		if c.Stopping() {
+			fmt.Fprintf(c.Output(), "stopped before %s\n", m.FileName)
+			return migrationsOutput, nil
+		}
+
+		// repeatable migrations are not recorded when skipped, so that they run once they are no longer skipped
+		if m.SkipReason != "" {
+			fmt.Fprintf(c.Output(), "%s/skipped (%s)\n", m.Name, m.SkipReason)
+			continue
+		}
+
+		start := time.Now()
+		c.emit(Event{Kind: EventMigrationStarted, Migration: m.FileName})
+
 		statementKind := cmp.Or(m.Directives.StatementKind, m.Kind)
 		var rowsAffected int64
 		switch statementKind {
 		case StatementKindDDL:
-			if err := c.ApplyDDL(ctx, m.Statements, protoDescriptors); err != nil {
+			err := withMigrationTimeout(ctx, []*Migration{m}, func(ctx context.Context) error {
+				return c.ApplyDDL(ctx, m.Statements, protoDescriptors)
+			})
+			if err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
 					err:  err,
 				}
 			}
 		case StatementKindDML:
-			count, err := c.ApplyDML(ctx, m.Statements)
+			count, err := c.applyMigrationDML(ctx, m, StatementKindDML, partitionedConcurrency)
 			if err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
@@ -1092,7 +1480,7 @@ func (c *Client) ExecuteRepeatableMigrations(ctx context.Context, migrations Mig
 			}
 			rowsAffected = count
 		case StatementKindPartitionedDML:
-			count, err := c.ApplyPartitionedDML(ctx, m.Statements, partitionedConcurrency)
+			count, err := c.applyMigrationDML(ctx, m, StatementKindPartitionedDML, partitionedConcurrency)
 			if err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
@@ -1101,7 +1489,7 @@ func (c *Client) ExecuteRepeatableMigrations(ctx context.Context, migrations Mig
 			}
 			rowsAffected = count
 		case StatementKindConvergentDML:
-			count, err := convergentApply(ctx, c.ApplyDML, m.Statements, m.Directives.Concurrency)
+			count, err := c.applyMigrationDML(ctx, m, StatementKindConvergentDML, partitionedConcurrency)
 			if err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
@@ -1116,6 +1504,10 @@ func (c *Client) ExecuteRepeatableMigrations(ctx context.Context, migrations Mig
 			}
 		}
 
+		if err := c.runAssertions(ctx, m); err != nil {
+			return nil, err
+		}
+
 		_, err := c.spannerClient.Apply(ctx, []*spanner.Mutation{
 			spanner.InsertOrUpdate(tableName, []string{"Name", "Checksum", "AppliedAt"}, []interface{}{m.Name, m.Checksum, spanner.CommitTimestamp}),
 		})
@@ -1126,28 +1518,32 @@ func (c *Client) ExecuteRepeatableMigrations(ctx context.Context, migrations Mig
 			}
 		}
 
-		fmt.Printf("R/up %s\n", m.Name)
+		c.emit(Event{
+			Kind:         EventMigrationCompleted,
+			Migration:    m.FileName,
+			RowsAffected: rowsAffected,
+			Elapsed:      time.Since(start),
+		})
+		if m.IsSeed {
+			fmt.Fprintf(c.Output(), "S/up %s\n", m.Name)
+		} else {
+			fmt.Fprintf(c.Output(), "R/up %s\n", m.Name)
+		}
 		migrationsOutput[m.FileName] = migrationInfo{RowsAffected: rowsAffected}
 	}
 
 	return migrationsOutput, nil
 }
 
+// statementKindSkipped is the kind of a batch of skipped migrations, which are recorded without being executed.
+const statementKindSkipped StatementKind = "Skipped"
+
 // migrationBatch represents a group of contiguous migrations of the same type
 type migrationBatch struct {
 	migrations []*Migration
 	kind       StatementKind
 }
 
-// statements aggregates all SQL statements from all migrations in the batch
-func (b *migrationBatch) statements() []string {
-	var allStatements []string
-	for _, m := range b.migrations {
-		allStatements = append(allStatements, m.Statements...)
-	}
-	return allStatements
-}
-
 // versions returns a slice of all migration versions in the batch
 func (b *migrationBatch) versions() []uint {
 	versions := make([]uint, len(b.migrations))
@@ -1162,6 +1558,13 @@ func (b *migrationBatch) versions() []uint {
 func hasOutOfOrderMigrations(migrations Migrations, applied map[int64]bool) bool {
 	appliedVersions := slices.Sorted(maps.Keys(applied))
 
+	// versions below the first migration were squashed into a baseline or archived, so they cannot be out of order
+	if len(migrations) > 0 {
+		appliedVersions = slices.DeleteFunc(appliedVersions, func(v int64) bool {
+			return v < int64(migrations[0].Version)
+		})
+	}
+
 	// all applied versions must match the initial segment of migrations otherwise there are out-of-order migrations
 	for i, version := range appliedVersions {
 		if migrations[i].Version != uint(version) {
@@ -1191,7 +1594,7 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 	batches := groupMigrationsByType(migrations, applied, limit)
 
 	if len(batches) > 0 {
-		fmt.Printf("Fast-forward migrations enabled: grouped into %d batch(es)\n", len(batches))
+		fmt.Fprintf(c.Output(), "Fast-forward migrations enabled: grouped into %d batch(es)\n", len(batches))
 	}
 
 	for _, batch := range batches {
@@ -1199,14 +1602,31 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 			break
 		}
 
+		if c.Stopping() {
+			fmt.Fprintf(c.Output(), "stopped before versions %v\n", batch.versions())
+			return migrationsOutput, nil
+		}
+
+		if batch.kind == statementKindSkipped {
+			for _, m := range batch.migrations {
+				if err := c.skipMigration(ctx, m, tableName); err != nil {
+					return nil, &Error{
+						Code: ErrorCodeExecuteMigrations,
+						err:  err,
+					}
+				}
+			}
+			continue
+		}
+
 		// Log batch information
 		if len(batch.migrations) > 1 {
-			fmt.Printf("Batching %d %s migrations:\n", len(batch.migrations), batch.kind)
+			fmt.Fprintf(c.Output(), "Batching %d %s migrations:\n", len(batch.migrations), batch.kind)
 			for _, m := range batch.migrations {
 				if m.Name != "" {
-					fmt.Printf("  - %d: %s\n", m.Version, m.Name)
+					fmt.Fprintf(c.Output(), "  - %d: %s\n", m.Version, m.Name)
 				} else {
-					fmt.Printf("  - %d\n", m.Version)
+					fmt.Fprintf(c.Output(), "  - %d\n", m.Version)
 				}
 			}
 		}
@@ -1221,14 +1641,19 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 			}
 		}
 
+		start := time.Now()
+		for _, m := range batch.migrations {
+			c.emit(Event{Kind: EventMigrationStarted, Migration: m.FileName})
+		}
+
 		// Execute the batch based on its type
 		switch batch.kind {
 		case StatementKindDDL:
 			if len(batch.migrations) > 1 {
-				fmt.Printf("Applying versions %v in a single UpdateDatabaseDdlRequest\n", batch.versions())
+				fmt.Fprintf(c.Output(), "Applying versions %v in a single UpdateDatabaseDdlRequest\n", batch.versions())
 			}
 
-			if err := c.ApplyDDL(ctx, batch.statements(), protoDescriptors); err != nil {
+			if err := c.applyMigrationDDL(ctx, tableName, batch.migrations, protoDescriptors); err != nil {
 				return nil, &Error{
 					Code: ErrorCodeExecuteMigrations,
 					err:  err,
@@ -1237,7 +1662,7 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 
 		case StatementKindDML:
 			for _, m := range batch.migrations {
-				rowsAffected, err := c.ApplyDML(ctx, m.Statements)
+				rowsAffected, err := c.applyMigrationDML(ctx, m, StatementKindDML, partitionedConcurrency)
 				if err != nil {
 					return nil, &Error{
 						Code: ErrorCodeExecuteMigrations,
@@ -1251,7 +1676,7 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 
 		case StatementKindPartitionedDML:
 			for _, m := range batch.migrations {
-				rowsAffected, err := c.ApplyPartitionedDML(ctx, m.Statements, partitionedConcurrency)
+				rowsAffected, err := c.applyMigrationDML(ctx, m, StatementKindPartitionedDML, partitionedConcurrency)
 				if err != nil {
 					return nil, &Error{
 						Code: ErrorCodeExecuteMigrations,
@@ -1265,7 +1690,21 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 
 		case StatementKindConvergentDML:
 			for _, m := range batch.migrations {
-				rowsAffected, err := convergentApply(ctx, c.ApplyDML, m.Statements, m.Directives.Concurrency)
+				rowsAffected, err := c.applyMigrationDML(ctx, m, StatementKindConvergentDML, partitionedConcurrency)
+				if err != nil {
+					return nil, &Error{
+						Code: ErrorCodeExecuteMigrations,
+						err:  err,
+					}
+				}
+				migrationsOutput[m.FileName] = migrationInfo{
+					RowsAffected: rowsAffected,
+				}
+			}
+
+		case StatementKindBaseline:
+			for _, m := range batch.migrations {
+				rowsAffected, err := c.applyBaseline(ctx, tableName, m, protoDescriptors)
 				if err != nil {
 					return nil, &Error{
 						Code: ErrorCodeExecuteMigrations,
@@ -1284,12 +1723,22 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 			}
 		}
 
+		if err := c.assertMigrations(ctx, tableName, batch.migrations); err != nil {
+			return nil, err
+		}
+
 		// Mark all migrations in batch as clean and print status
 		for _, m := range batch.migrations {
+			c.emit(Event{
+				Kind:         EventMigrationCompleted,
+				Migration:    m.FileName,
+				RowsAffected: migrationsOutput[m.FileName].RowsAffected,
+				Elapsed:      time.Since(start),
+			})
 			if m.Name != "" {
-				fmt.Printf("%d/up %s\n", m.Version, m.Name)
+				fmt.Fprintf(c.Output(), "%d/up %s\n", m.Version, m.Name)
 			} else {
-				fmt.Printf("%d/up\n", m.Version)
+				fmt.Fprintf(c.Output(), "%d/up\n", m.Version)
 			}
 
 			if err := c.setSchemaMigrationVersion(ctx, m.Version, false, tableName); err != nil {
@@ -1307,7 +1756,7 @@ func (c *Client) executeFFMigrations(ctx context.Context, migrations Migrations,
 	}
 
 	if count == 0 {
-		fmt.Println("no change")
+		fmt.Fprintln(c.Output(), "no change")
 	}
 
 	return migrationsOutput, nil
@@ -1335,6 +1784,9 @@ func groupMigrationsByType(migrations Migrations, applied map[int64]bool, limit
 
 		// Determine the effective kind (considering directives)
 		effectiveKind := cmp.Or(m.Directives.StatementKind, m.Kind)
+		if m.SkipReason != "" {
+			effectiveKind = statementKindSkipped
+		}
 
 		if currentBatch == nil || currentBatch.kind != effectiveKind {
 			// Start a new batch
@@ -1350,7 +1802,9 @@ func groupMigrationsByType(migrations Migrations, applied map[int64]bool, limit
 			currentBatch.migrations = append(currentBatch.migrations, m)
 		}
 
-		count++
+		if effectiveKind != statementKindSkipped {
+			count++
+		}
 	}
 
 	// Add the last batch if it exists
@@ -1365,10 +1819,21 @@ func (c *Client) GetSchemaMigrationVersion(ctx context.Context, tableName string
 	stmt := spanner.Statement{
 		SQL: `SELECT Version, Dirty FROM ` + tableName + ` LIMIT 1`,
 	}
-	iter := c.spannerClient.Single().Query(ctx, stmt)
-	defer iter.Stop()
 
-	row, err := iter.Next()
+	var (
+		v     int64
+		dirty bool
+	)
+	err := c.retry(ctx, func(ctx context.Context) error {
+		iter := c.spannerClient.Single().Query(ctx, stmt)
+		defer iter.Stop()
+
+		row, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		return row.Columns(&v, &dirty)
+	})
 	if err != nil {
 		if err == iterator.Done {
 			return 0, false, &Error{
@@ -1382,29 +1847,21 @@ func (c *Client) GetSchemaMigrationVersion(ctx context.Context, tableName string
 		}
 	}
 
-	var (
-		v     int64
-		dirty bool
-	)
-	if err := row.Columns(&v, &dirty); err != nil {
-		return 0, false, &Error{
-			Code: ErrorCodeGetMigrationVersion,
-			err:  err,
-		}
-	}
-
 	return uint(v), dirty, nil
 }
 
 // setSchemaMigrationVersion will set a specific version in the version and history table without checking existing state
 func (c *Client) setSchemaMigrationVersion(ctx context.Context, version uint, dirty bool, tableName string) error {
-	_, err := c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
-		m := setSchemaVersionMutations(tableName, version, dirty)
-		if err := tx.BufferWrite(m); err != nil {
-			return err
-		}
+	err := c.retry(ctx, func(ctx context.Context) error {
+		_, err := c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+			m := setSchemaVersionMutations(tableName, version, dirty)
+			if err := tx.BufferWrite(m); err != nil {
+				return err
+			}
 
-		return c.upsertVersionHistory(ctx, tx, int64(version), dirty, tableName+historyStr)
+			return c.upsertVersionHistory(ctx, tx, int64(version), dirty, tableName+historyStr)
+		})
+		return err
 	})
 	if err != nil {
 		return &Error{
@@ -1497,7 +1954,10 @@ func (c *Client) Close() error {
 	return nil
 }
 
-func (c *Client) EnsureMigrationTable(ctx context.Context, tableName string) error {
+// EnsureMigrationTable creates or upgrades the migration tracking tables of tableName, and creates the lock table if it
+// does not exist. The lock table is shared by every version table of the database, e.g. of each stream, so that
+// migrations are never run at the same time.
+func (c *Client) EnsureMigrationTable(ctx context.Context, tableName, lockTableName string) error {
 	fmtErr := func(err error) *Error {
 		return &Error{
 			Code: ErrorCodeEnsureMigrationTables,
@@ -1517,9 +1977,6 @@ func (c *Client) EnsureMigrationTable(ctx context.Context, tableName string) err
 		if err := c.createHistoryTable(ctx, tableName+historyStr); err != nil {
 			return fmtErr(err)
 		}
-		if err := c.SetupMigrationLock(ctx, tableName+lockStr); err != nil {
-			return fmtErr(err)
-		}
 	case ExistingMigrationsNoUpgrade:
 		if err := c.createUpgradeIndicatorTable(ctx); err != nil {
 			return fmtErr(err)
@@ -1527,7 +1984,15 @@ func (c *Client) EnsureMigrationTable(ctx context.Context, tableName string) err
 		if err := c.createHistoryTable(ctx, tableName+historyStr); err != nil {
 			return fmtErr(err)
 		}
-		if err := c.SetupMigrationLock(ctx, tableName+lockStr); err != nil {
+	}
+
+	if err := c.ensureHistoryColumns(ctx, tableName+historyStr); err != nil {
+		return fmtErr(err)
+	}
+
+	// an existing lock table is not set up again, as that would clear a lock taken for another version table
+	if !c.tableExists(ctx, lockTableName) {
+		if err := c.SetupMigrationLock(ctx, lockTableName); err != nil {
 			return fmtErr(err)
 		}
 	}
@@ -1535,6 +2000,48 @@ func (c *Client) EnsureMigrationTable(ctx context.Context, tableName string) err
 	return nil
 }
 
+// historyColumns are the nullable columns added to the history table after it was first introduced.
+var historyColumns = []struct {
+	name string
+	ddl  string
+}{
+	{name: "OperationName", ddl: "OperationName STRING(MAX)"},
+	{name: "CommittedStatements", ddl: "CommittedStatements INT64"},
+	{name: "FailureMessage", ddl: "FailureMessage STRING(MAX)"},
+	{name: "SkipReason", ddl: "SkipReason STRING(MAX)"},
+}
+
+// ensureHistoryColumns adds any columns missing from history tables created by an older version of wrench.
+func (c *Client) ensureHistoryColumns(ctx context.Context, historyTableName string) error {
+	stmt := spanner.NewStatement("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_CATALOG = '' AND TABLE_SCHEMA = '' AND TABLE_NAME = @table")
+	stmt.Params["table"] = historyTableName
+
+	existing := make(map[string]bool)
+	err := c.spannerClient.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var name string
+		if err := r.Column(0, &name); err != nil {
+			return err
+		}
+		existing[name] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var statements []string
+	for _, column := range historyColumns {
+		if !existing[column.name] {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", historyTableName, column.ddl))
+		}
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+
+	return c.ApplyDDL(ctx, statements, nil)
+}
+
 func (c *Client) EnsureRepeatableMigrationTable(ctx context.Context, tableName string) error {
 	if c.tableExists(ctx, tableName) {
 		return nil
@@ -1604,7 +2111,11 @@ func (c *Client) createHistoryTable(ctx context.Context, historyTableName string
     Version INT64 NOT NULL,
 	Dirty BOOL NOT NULL,
 	Created TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),
-	Modified TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true)
+	Modified TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),
+	OperationName STRING(MAX),
+	CommittedStatements INT64,
+	FailureMessage STRING(MAX),
+	SkipReason STRING(MAX)
 	) PRIMARY KEY(Version)`, historyTableName)
 
 	return c.ApplyDDL(ctx, []string{stmt}, nil)
@@ -1668,7 +2179,7 @@ func (c *Client) SetupMigrationLock(ctx context.Context, tableName string) error
 		if err != nil {
 			return err
 		}
-		fmt.Printf("clearing lock identifier [%s] expiry [%v]\n", lock.LockIdentifier, lock.Expiry)
+		fmt.Fprintf(c.Output(), "clearing lock identifier [%s] expiry [%v]\n", lock.LockIdentifier, lock.Expiry)
 
 		// update
 		return trx.BufferWrite([]*spanner.Mutation{
@@ -1691,32 +2202,36 @@ func (c *Client) GetMigrationLock(ctx context.Context, tableName, lockIdentifier
 		lock.Success = true
 		return lock, err
 	}
-	_, err = c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, trx *spanner.ReadWriteTransaction) error {
-		sql := fmt.Sprintf(`UPDATE %s SET LockIdentifier=@lockIdentifier, 
-		Expiry = TIMESTAMP_ADD(CURRENT_TIMESTAMP(), INTERVAL 30 MINUTE) 
-		WHERE ID IS NULL AND (LockIdentifier IS NULL OR CURRENT_TIMESTAMP() > Expiry)`, tableName)
-		lockStmt := spanner.NewStatement(sql)
-		lockStmt.Params["lockIdentifier"] = lockIdentifier
-		rc, err := trx.Update(ctx, lockStmt)
-		if err != nil {
-			return err
-		}
+	// the lock is re-entrant for the same identifier, so that taking the lock can be retried
+	err = c.retry(ctx, func(ctx context.Context) error {
+		_, err := c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, trx *spanner.ReadWriteTransaction) error {
+			sql := fmt.Sprintf(`UPDATE %s SET LockIdentifier=@lockIdentifier, 
+			Expiry = TIMESTAMP_ADD(CURRENT_TIMESTAMP(), INTERVAL 30 MINUTE) 
+			WHERE ID IS NULL AND (LockIdentifier IS NULL OR LockIdentifier = @lockIdentifier OR CURRENT_TIMESTAMP() > Expiry)`, tableName)
+			lockStmt := spanner.NewStatement(sql)
+			lockStmt.Params["lockIdentifier"] = lockIdentifier
+			rc, err := trx.Update(ctx, lockStmt)
+			if err != nil {
+				return err
+			}
 
-		lock.Success = rc == 1
+			lock.Success = rc == 1
 
-		sql2 := fmt.Sprintf("Select LockIdentifier, Expiry FROM %s WHERE ID IS NULL", tableName)
-		err = trx.Query(ctx, spanner.NewStatement(sql2)).
-			Do(func(r *spanner.Row) error {
-				if err := r.ToStruct(&lock); err != nil {
-					return err
-				}
-				return nil
-			})
-		if err != nil {
-			return err
-		}
+			sql2 := fmt.Sprintf("Select LockIdentifier, Expiry FROM %s WHERE ID IS NULL", tableName)
+			err = trx.Query(ctx, spanner.NewStatement(sql2)).
+				Do(func(r *spanner.Row) error {
+					if err := r.ToStruct(&lock); err != nil {
+						return err
+					}
+					return nil
+				})
+			if err != nil {
+				return err
+			}
 
-		return nil
+			return nil
+		})
+		return err
 	})
 	if err != nil {
 		return lock, err
@@ -1725,9 +2240,10 @@ func (c *Client) GetMigrationLock(ctx context.Context, tableName, lockIdentifier
 	// fmt.Printf("%v %s %v\n", lock.Success, lock.LockIdentifier, lock.Expiry)
 
 	lock.Release = func() {
-		err = c.releaseMigrationLock(ctx, tableName, lockIdentifier)
+		// release the lock even if the migration was cancelled
+		err = c.releaseMigrationLock(context.WithoutCancel(ctx), tableName, lockIdentifier)
 		if err != nil {
-			fmt.Printf("failed to release migration lock: %v\n", err)
+			fmt.Fprintf(c.Output(), "failed to release migration lock: %v\n", err)
 		}
 	}
 
@@ -1735,6 +2251,12 @@ func (c *Client) GetMigrationLock(ctx context.Context, tableName, lockIdentifier
 }
 
 func (c *Client) releaseMigrationLock(ctx context.Context, tableName, lockIdentifier string) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		return c.releaseMigrationLockOnce(ctx, tableName, lockIdentifier)
+	})
+}
+
+func (c *Client) releaseMigrationLockOnce(ctx context.Context, tableName, lockIdentifier string) error {
 	_, err := c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, trx *spanner.ReadWriteTransaction) error {
 		sql := fmt.Sprintf("Update %s SET LockIdentifier=NULL, Expiry=NULL WHERE ID IS NULL AND LockIdentifier=@lockIdentifier", tableName)
 		stmt := spanner.NewStatement(sql)
@@ -1752,6 +2274,79 @@ func (c *Client) releaseMigrationLock(ctx context.Context, tableName, lockIdenti
 	return nil
 }
 
+// applyMigrationDML applies the DML statements of the migration, retrying after a transient error if the migration has
+// the Retry directive.
+func (c *Client) applyMigrationDML(ctx context.Context, m *Migration, kind StatementKind, partitionedConcurrency int) (int64, error) {
+	policy := RetryPolicy{
+		Attempts: m.Directives.Retry + 1,
+		Backoff:  cmp.Or(c.config.RetryPolicy.Backoff, time.Second),
+	}
+
+	var rowsAffected int64
+	err := withMigrationTimeout(ctx, []*Migration{m}, func(ctx context.Context) error {
+		return retry(ctx, policy, func(ctx context.Context) (err error) {
+			switch kind {
+			case StatementKindPartitionedDML:
+				rowsAffected, err = c.ApplyPartitionedDML(ctx, m.Statements, partitionedConcurrency)
+			case StatementKindConvergentDML:
+				rowsAffected, err = c.applyConvergentDML(ctx, m.Statements, m.Directives.Concurrency)
+			default:
+				rowsAffected, err = c.ApplyDML(ctx, m.Statements)
+			}
+			return err
+		})
+	})
+
+	return rowsAffected, err
+}
+
+// withMigrationTimeout calls f with the deadline set by the Timeout directive of the migrations. Migrations applied in
+// a single batch share the sum of their timeouts, and have no deadline if any of them has no timeout. If the deadline
+// is exceeded an error naming the migrations is returned.
+func withMigrationTimeout(ctx context.Context, migrations []*Migration, f func(ctx context.Context) error) error {
+	var timeout time.Duration
+	var names []string
+	for _, m := range migrations {
+		if m.Directives.Timeout == 0 {
+			return f(ctx)
+		}
+		timeout += m.Directives.Timeout
+		names = append(names, m.FileName)
+	}
+	if len(names) == 0 {
+		return f(ctx)
+	}
+
+	timeoutErr := &Error{
+		Code: ErrorCodeMigrationTimeout,
+		err:  fmt.Errorf("migration %s exceeded its timeout of %v", strings.Join(names, ", "), timeout),
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, timeoutErr)
+	defer cancel()
+
+	err := f(ctx)
+	if err != nil && context.Cause(ctx) == timeoutErr {
+		return timeoutErr
+	}
+	return err
+}
+
+// applyConvergentDML applies the statements with convergentApply, emitting the running row count.
+func (c *Client) applyConvergentDML(ctx context.Context, statements []string, concurrency int) (int64, error) {
+	progress := c.startDMLProgress(len(statements))
+	defer progress.stop()
+
+	applyDML := func(ctx context.Context, statements []string) (int64, error) {
+		rowCount, err := c.ApplyDML(ctx, statements)
+		if err == nil {
+			progress.addRows(rowCount)
+		}
+		return rowCount, err
+	}
+
+	return convergentApply(ctx, applyDML, statements, concurrency)
+}
+
 func convergentApply(ctx context.Context, applyDMLFunc func(context.Context, []string) (int64, error), statements []string, concurrency int) (int64, error) {
 	concurrency = max(concurrency, 1)
 	p := pool.New().WithMaxGoroutines(concurrency).WithErrors()
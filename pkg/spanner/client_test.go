@@ -41,8 +41,9 @@ import (
 )
 
 const (
-	singerTable    = "Singers"
-	migrationTable = "SchemaMigrations"
+	singerTable        = "Singers"
+	migrationTable     = "SchemaMigrations"
+	migrationLockTable = "SchemaMigrationsLock"
 )
 
 type (
@@ -195,6 +196,119 @@ func TestExecuteRepeatableMigrations_EndToEnd(t *testing.T) {
 	assert.Len(t, output, 1)
 }
 
+func TestExecuteRepeatableMigrations_RunAlways(t *testing.T) {
+	ctx := context.Background()
+
+	client, done := testClientWithDatabase(t, ctx)
+	defer done()
+
+	repeatableTableName := "RepeatableHistoryRunAlways"
+	require.NoError(t, client.EnsureRepeatableMigrationTable(ctx, repeatableTableName))
+
+	dir := t.TempDir()
+	newFile(t, dir, "R__base.sql", []byte("CREATE OR REPLACE VIEW Base SQL SECURITY INVOKER AS SELECT 1 AS Col1"))
+	newFile(t, dir, "R__always.sql", []byte(`-- @wrench.RunAlways
+-- @wrench.DependsOn=R__base
+CREATE OR REPLACE VIEW Always SQL SECURITY INVOKER AS SELECT Col1 FROM Base`))
+
+	ms, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+	require.NoError(t, err)
+	require.Len(t, ms, 2)
+	assert.Equal(t, "base", ms[0].Name)
+
+	output, err := client.ExecuteRepeatableMigrations(ctx, ms, repeatableTableName, 1, nil)
+	require.NoError(t, err)
+	assert.Len(t, output, 2)
+
+	// only the run always migration is executed when unchanged
+	output, err = client.ExecuteRepeatableMigrations(ctx, ms, repeatableTableName, 1, nil)
+	require.NoError(t, err)
+	assert.Len(t, output, 1)
+	assert.Contains(t, output, "R__always.sql")
+
+	require.NoError(t, client.DeleteRepeatableMigrationHistory(ctx, repeatableTableName, []string{"always"}))
+	history, err := client.GetRepeatableMigrationHistory(ctx, repeatableTableName)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "base", history[0].Name)
+}
+
+func TestExecuteMigrations_Baseline(t *testing.T) {
+	ctx := context.Background()
+
+	client, done := testClientWithDatabase(t, ctx)
+	defer done()
+
+	dir := t.TempDir()
+	newFile(t, dir, "000100_baseline.sql", []byte(`-- @wrench.StatementKind=Baseline
+CREATE TABLE Baselined (
+  ID STRING(36) NOT NULL,
+) PRIMARY KEY(ID);
+INSERT INTO Baselined (ID) VALUES ('1');`))
+	baseline, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+	require.NoError(t, err)
+
+	// a new database applies the schema and static data of the baseline
+	freshTableName := "SchemaMigrationsFresh"
+	require.NoError(t, client.EnsureMigrationTable(ctx, freshTableName, migrationLockTable))
+	output, err := client.ExecuteMigrations(ctx, baseline, -1, freshTableName, 1, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), output["000100_baseline.sql"].RowsAffected)
+
+	// a database that applied a squashed migration records the baseline without executing it, which would fail as the
+	// table already exists
+	squashedTableName := "SchemaMigrationsSquashed"
+	require.NoError(t, client.EnsureMigrationTable(ctx, squashedTableName, migrationLockTable))
+	squashedDir := t.TempDir()
+	newFile(t, squashedDir, "000001_squashed.sql", []byte(`UPDATE Baselined SET ID = '1' WHERE ID = '1';`))
+	squashed, err := LoadMigrations(squashedDir, nil, false, PlaceholderOptions{})
+	require.NoError(t, err)
+	_, err = client.ExecuteMigrations(ctx, squashed, -1, squashedTableName, 1, nil, false)
+	require.NoError(t, err)
+
+	output, err = client.ExecuteMigrations(ctx, baseline, -1, squashedTableName, 1, nil, false)
+	require.NoError(t, err)
+	assert.Empty(t, output)
+
+	history, err := client.GetMigrationHistory(ctx, squashedTableName)
+	require.NoError(t, err)
+	var versions []int64
+	for _, h := range history {
+		versions = append(versions, h.Version)
+	}
+	assert.ElementsMatch(t, []int64{1, 100}, versions)
+}
+
+func TestExecuteSeeds(t *testing.T) {
+	ctx := context.Background()
+
+	client, done := testClientWithDatabase(t, ctx)
+	defer done()
+
+	seedTableName := SeedHistoryTableName("SchemaMigrations")
+	require.NoError(t, client.EnsureRepeatableMigrationTable(ctx, seedTableName))
+
+	dir := t.TempDir()
+	newFile(t, dir, "S__singers.sql", []byte(`INSERT OR UPDATE INTO Singers (SingerID, FirstName) VALUES ('seed-1', 'Seed');`))
+
+	seeds, err := LoadSeeds(dir, false, PlaceholderOptions{})
+	require.NoError(t, err)
+	output, err := client.ExecuteRepeatableMigrations(ctx, seeds, seedTableName, 1, nil)
+	require.NoError(t, err)
+	assert.Len(t, output, 1)
+
+	// unchanged seeds are not applied again
+	output, err = client.ExecuteRepeatableMigrations(ctx, seeds, seedTableName, 1, nil)
+	require.NoError(t, err)
+	assert.Empty(t, output)
+
+	history, err := client.GetRepeatableMigrationHistory(ctx, seedTableName)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "singers", history[0].Name)
+	assert.Equal(t, seeds[0].Checksum, history[0].Checksum)
+}
+
 func TestApplyDDLFile(t *testing.T) {
 	ctx := context.Background()
 
@@ -627,7 +741,7 @@ func TestEnsureMigrationTable(t *testing.T) {
 			client, done := testClientWithDatabase(t, ctx)
 			defer done()
 
-			if err := client.EnsureMigrationTable(ctx, test.table); err != nil {
+			if err := client.EnsureMigrationTable(ctx, test.table, migrationLockTable); err != nil {
 				t.Fatalf("failed to ensure migration table: %v", err)
 			}
 
@@ -655,11 +769,37 @@ func TestEnsureMigrationTable(t *testing.T) {
 		})
 	}
 
+	t.Run("creates the shared lock table", func(t *testing.T) {
+		client, done := testClientWithDatabase(t, ctx)
+		defer done()
+
+		// each stream has its own version table and shares the lock table
+		require.NoError(t, client.EnsureMigrationTable(ctx, migrationTable, "MigrationsLock"))
+		require.NoError(t, client.EnsureMigrationTable(ctx, "billing_"+migrationTable, "MigrationsLock"))
+
+		assert.True(t, client.tableExists(ctx, "MigrationsLock"))
+		assert.False(t, client.tableExists(ctx, migrationTable+"Lock"))
+		assert.False(t, client.tableExists(ctx, "billing_"+migrationTable+"Lock"))
+
+		lock, err := client.GetMigrationLock(ctx, "MigrationsLock", "default")
+		require.NoError(t, err)
+		defer lock.Release()
+		require.True(t, lock.Success)
+
+		// ensuring the tables of another stream does not clear the lock
+		require.NoError(t, client.EnsureMigrationTable(ctx, "search_"+migrationTable, "MigrationsLock"))
+		other, err := client.GetMigrationLock(ctx, "MigrationsLock", "billing")
+		require.NoError(t, err)
+		defer other.Release()
+		assert.False(t, other.Success)
+		assert.Equal(t, "default", other.LockIdentifier)
+	})
+
 	t.Run("also creates history table", func(t *testing.T) {
 		client, done := testClientWithDatabase(t, ctx)
 		defer done()
 
-		if err := client.EnsureMigrationTable(ctx, migrationTable); err != nil {
+		if err := client.EnsureMigrationTable(ctx, migrationTable, migrationLockTable); err != nil {
 			t.Fatalf("failed to ensure migration table: %v", err)
 		}
 
@@ -875,7 +1015,7 @@ func TestUpgrade(t *testing.T) {
 		if err := client.ApplyDDL(ctx, []string{"DROP TABLE " + migrationTable + historyStr}, nil); err != nil {
 			t.Fatalf("failed to drop migration history: %v", err)
 		}
-		if err := client.EnsureMigrationTable(ctx, migrationTable); err != nil {
+		if err := client.EnsureMigrationTable(ctx, migrationTable, migrationLockTable); err != nil {
 			t.Fatalf("failed to recreate migration table: %v", err)
 		}
 		if client.tableExists(ctx, upgradeIndicator) == false {
@@ -1263,6 +1403,33 @@ func TestClient_RepairMigration(t *testing.T) {
 	}
 }
 
+func TestClient_ResumeMigrations(t *testing.T) {
+	ctx := context.Background()
+	client, done := testClientWithDatabase(t, ctx)
+	defer done()
+
+	// simulate wrench being interrupted after the DDL operation was submitted
+	require.NoError(t, client.setSchemaMigrationVersion(ctx, 1, true, migrationTable))
+	op, err := client.submitDDL(ctx, []string{"ALTER TABLE Singers ADD COLUMN LastName STRING(MAX)"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, client.setMigrationOperation(ctx, migrationTable, []uint{1}, op.Name()))
+
+	resumed, err := client.ResumeMigrations(ctx, migrationTable)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, resumed)
+
+	ensureMigrationColumn(t, ctx, client, "LastName", "STRING(MAX)", "YES")
+	ensureMigrationVersionRecord(t, ctx, client, 1, false)
+	ensureMigrationHistoryRecord(t, ctx, client, 1, false)
+
+	// dirty versions without an operation cannot be resumed
+	require.NoError(t, client.setSchemaMigrationVersion(ctx, 2, true, migrationTable))
+	_, err = client.ResumeMigrations(ctx, migrationTable)
+	var se *Error
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, ErrorCode(ErrorCodeMigrationVersionDirty), se.Code)
+}
+
 func Test_MigrationInfoString(t *testing.T) {
 	tests := []struct {
 		testName        string
@@ -1397,6 +1564,54 @@ func Test_parseDDL1(t *testing.T) {
 	}
 }
 
+func Test_withMigrationTimeout(t *testing.T) {
+	waitForDeadline := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("NoTimeout", func(t *testing.T) {
+		err := withMigrationTimeout(context.Background(), []*Migration{{FileName: "001.sql"}}, func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			assert.False(t, ok)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Exceeded", func(t *testing.T) {
+		m := &Migration{FileName: "001_backfill.sql", Directives: MigrationDirectives{Timeout: time.Millisecond}}
+		err := withMigrationTimeout(context.Background(), []*Migration{m}, waitForDeadline)
+
+		var se *Error
+		require.ErrorAs(t, err, &se)
+		assert.Equal(t, ErrorCode(ErrorCodeMigrationTimeout), se.Code)
+		assert.Contains(t, err.Error(), "001_backfill.sql")
+	})
+
+	t.Run("BatchWithoutTimeout", func(t *testing.T) {
+		ms := []*Migration{
+			{FileName: "001.sql", Directives: MigrationDirectives{Timeout: time.Millisecond}},
+			{FileName: "002.sql"},
+		}
+		err := withMigrationTimeout(context.Background(), ms, func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			assert.False(t, ok)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ParentCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		m := &Migration{FileName: "001.sql", Directives: MigrationDirectives{Timeout: time.Hour}}
+		err := withMigrationTimeout(ctx, []*Migration{m}, waitForDeadline)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
 func Test_convergentApply(t *testing.T) {
 	// create a new "apply" function that executes each statement 11 times, the
 	// first 10 times returning 100 rows and the 11th time returning 0 rows.
@@ -1533,3 +1748,28 @@ func newFile(t *testing.T, dir, fileName string, content []byte) {
 	_, err = file.Write(content)
 	require.NoError(t, err)
 }
+
+func TestClient_ResumeMigrations_FailedAssertion(t *testing.T) {
+	ctx := context.Background()
+	client, done := testClientWithDatabase(t, ctx)
+	defer done()
+
+	// the DDL operation succeeds but the assertion of the migration fails
+	migrations := []*Migration{{
+		Version:    1,
+		FileName:   "000001_add_last_name.sql",
+		Statements: []string{"ALTER TABLE Singers ADD COLUMN LastName STRING(MAX)"},
+		Kind:       StatementKindDDL,
+		Assertions: []string{"SELECT FALSE"},
+	}}
+	_, err := client.ExecuteMigrations(ctx, migrations, -1, migrationTable, 1, nil, false)
+	var se *Error
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, ErrorCode(ErrorCodeMigrationAssertion), se.Code)
+
+	// the version is not resumed as the succeeded operation would bypass the assertion
+	_, err = client.ResumeMigrations(ctx, migrationTable)
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, ErrorCode(ErrorCodeMigrationVersionDirty), se.Code)
+	ensureMigrationVersionRecord(t, ctx, client, 1, true)
+}
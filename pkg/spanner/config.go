@@ -21,6 +21,7 @@ package spanner
 
 import (
 	"fmt"
+	"io"
 	"time"
 )
 
@@ -30,6 +31,16 @@ type Config struct {
 	Database        string
 	CredentialsFile string
 	StmtTimeout     time.Duration
+	// EventHandler is called with the progress of migrations and long-running operations.
+	EventHandler EventHandler
+	// RetryPolicy configures the retry of transient errors for idempotent operations, such as version bookkeeping,
+	// lock operations, history reads and DDL submission.
+	RetryPolicy RetryPolicy
+	// OutOfOrder is how pending migrations with a lower version than an applied migration are handled. Defaults to
+	// OutOfOrderAllow.
+	OutOfOrder OutOfOrderPolicy
+	// Output is where the result of each migration is written. Defaults to os.Stdout.
+	Output io.Writer
 }
 
 func (c *Config) URL() string {
@@ -0,0 +1,62 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+
+	admin "cloud.google.com/go/spanner/admin/database/apiv1"
+	databasepb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+var databaseURLRegex = regexp.MustCompile(`^projects/([^/]+)/instances/([^/]+)/databases/([^/]+)$`)
+
+// ParseDatabaseURL returns the project, instance and database of a database URL, e.g.
+// projects/my-project/instances/my-instance/databases/my-database.
+func ParseDatabaseURL(url string) (project, instance, database string, err error) {
+	matches := databaseURLRegex.FindStringSubmatch(url)
+	if matches == nil {
+		return "", "", "", fmt.Errorf("invalid database URL %q, expected projects/PROJECT/instances/INSTANCE/databases/DATABASE", url)
+	}
+	return matches[1], matches[2], matches[3], nil
+}
+
+// ListDatabases returns the IDs of the databases in the instance.
+func ListDatabases(ctx context.Context, project, instance, credentialsFile string) ([]string, error) {
+	opts := make([]option.ClientOption, 0)
+	if credentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(credentialsFile))
+	}
+
+	adminClient, err := admin.NewDatabaseAdminClient(ctx, opts...)
+	if err != nil {
+		return nil, &Error{
+			Code: ErrorCodeCreateClient,
+			err:  err,
+		}
+	}
+	defer adminClient.Close()
+
+	var databases []string
+	iter := adminClient.ListDatabases(ctx, &databasepb.ListDatabasesRequest{
+		Parent: fmt.Sprintf("projects/%s/instances/%s", project, instance),
+	})
+	for {
+		db, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, &Error{
+				Code: ErrorCodeListDatabases,
+				err:  err,
+			}
+		}
+		databases = append(databases, path.Base(db.GetName()))
+	}
+
+	return databases, nil
+}
@@ -0,0 +1,65 @@
+package spanner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ArchiveDirName is the name of the subdirectory of a migrations directory that is not loaded, e.g. the migrations
+// archived by migrate squash.
+const ArchiveDirName = "archive"
+
+// MigrationFile is a file in a migrations directory or one of its subdirectories.
+type MigrationFile struct {
+	// Dir is the migrations directory the file was found in.
+	Dir string
+	// FileName is the path of the file relative to Dir, separated by forward slashes.
+	FileName string
+}
+
+// Path returns the path of the file.
+func (f MigrationFile) Path() string {
+	return filepath.Join(f.Dir, filepath.FromSlash(f.FileName))
+}
+
+// MigrationsDirs splits a list of migrations directories joined by os.PathListSeparator, e.g.
+// migrations:shared/migrations. The first directory is where new migration files and the sum file are written.
+func MigrationsDirs(dir string) []string {
+	return filepath.SplitList(dir)
+}
+
+// ListMigrationFiles returns the files in the migrations directories and their subdirectories, except for archive
+// directories. dir may be a list of directories joined by os.PathListSeparator.
+func ListMigrationFiles(dir string) ([]MigrationFile, error) {
+	var files []MigrationFile
+	for _, root := range MigrationsDirs(dir) {
+		if _, err := os.Stat(root); err != nil {
+			return nil, err
+		}
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && d.Name() == ArchiveDirName {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, MigrationFile{Dir: root, FileName: filepath.ToSlash(rel)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -42,6 +42,11 @@ const (
 	ErrorCodeEnsureMigrationTables
 	ErrorCodeCompleteUpgrade
 	ErrorCodeUndirtyMigration
+	ErrorCodeResumeMigration
+	ErrorCodeMigrationTimeout
+	ErrorCodeMigrationAssertion
+	ErrorCodeMigrationOrder
+	ErrorCodeListDatabases
 )
 
 type Error struct {
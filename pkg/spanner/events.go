@@ -0,0 +1,163 @@
+package spanner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	admin "cloud.google.com/go/spanner/admin/database/apiv1"
+)
+
+const (
+	// EventMigrationStarted is emitted before a migration is executed.
+	EventMigrationStarted EventKind = "MigrationStarted"
+	// EventMigrationCompleted is emitted after a migration has been executed successfully.
+	EventMigrationCompleted EventKind = "MigrationCompleted"
+	// EventDDLProgress is emitted each time the metadata of a DDL operation is polled.
+	EventDDLProgress EventKind = "DDLProgress"
+	// EventDMLProgress is emitted as partitioned or convergent DML statements affect rows, and periodically while
+	// they are running.
+	EventDMLProgress EventKind = "DMLProgress"
+
+	// progressInterval is the longest interval between progress events of a long-running operation.
+	progressInterval = 10 * time.Second
+)
+
+type (
+	EventKind string
+
+	// Event reports the progress of a migration to an EventHandler.
+	Event struct {
+		Kind EventKind
+
+		// Migration is the file name of the migration. Only set for migration started and completed events.
+		Migration string
+
+		// Statement is the statement currently being executed by a DDL operation.
+		Statement string
+		// StatementIndex is the zero-based index of Statement for DDL progress, or the number of statements that have
+		// completed for DML progress.
+		StatementIndex int
+		StatementCount int
+		// ProgressPercent is the progress of Statement as reported by the DDL operation metadata.
+		ProgressPercent int32
+		// CommitTimestamp is the time Statement was committed, zero until it has been committed.
+		CommitTimestamp time.Time
+		// Throttled is true if the DDL operation is throttled, e.g. due to resource constraints.
+		Throttled bool
+
+		// RowsAffected is the running count of rows affected by DML statements.
+		RowsAffected int64
+		Elapsed      time.Duration
+		// Done is true for the final progress event of an operation.
+		Done bool
+	}
+
+	// EventHandler receives events as migrations are executed. Handlers may be called concurrently when DML
+	// statements are executed concurrently.
+	EventHandler func(Event)
+)
+
+func (c *Client) emit(e Event) {
+	if c.config.EventHandler != nil {
+		c.config.EventHandler(e)
+	}
+}
+
+// emitDDLProgress reports the progress of the statement currently being executed by the operation.
+func (c *Client) emitDDLProgress(op *admin.UpdateDatabaseDdlOperation, start time.Time) {
+	if c.config.EventHandler == nil {
+		return
+	}
+
+	meta, err := op.Metadata()
+	if err != nil || meta == nil || len(meta.Statements) == 0 {
+		return
+	}
+
+	// the current statement is the first one without a commit timestamp
+	current := min(len(meta.CommitTimestamps), len(meta.Statements)-1)
+	e := Event{
+		Kind:           EventDDLProgress,
+		Statement:      meta.Statements[current],
+		StatementIndex: current,
+		StatementCount: len(meta.Statements),
+		Throttled:      meta.Throttled,
+		Elapsed:        time.Since(start),
+		Done:           op.Done(),
+	}
+	if current < len(meta.Progress) {
+		e.ProgressPercent = meta.Progress[current].GetProgressPercent()
+	}
+	if current < len(meta.CommitTimestamps) {
+		e.CommitTimestamp = meta.CommitTimestamps[current].AsTime()
+	}
+
+	c.emit(e)
+}
+
+// dmlProgress tracks the running row count of DML statements, emitting progress as rows are affected and on each tick
+// of the progress interval.
+type dmlProgress struct {
+	client         *Client
+	start          time.Time
+	statementCount int
+	completed      atomic.Int64
+	rows           atomic.Int64
+	stopOnce       sync.Once
+	done           chan struct{}
+}
+
+func (c *Client) startDMLProgress(statementCount int) *dmlProgress {
+	p := &dmlProgress{
+		client:         c,
+		start:          time.Now(),
+		statementCount: statementCount,
+		done:           make(chan struct{}),
+	}
+
+	if c.config.EventHandler != nil {
+		go func() {
+			ticker := time.NewTicker(progressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-p.done:
+					return
+				case <-ticker.C:
+					p.emit(false)
+				}
+			}
+		}()
+	}
+
+	return p
+}
+
+func (p *dmlProgress) addRows(rows int64) {
+	p.rows.Add(rows)
+	p.emit(false)
+}
+
+func (p *dmlProgress) statementDone(rows int64) {
+	p.completed.Add(1)
+	p.addRows(rows)
+}
+
+func (p *dmlProgress) stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+		p.emit(true)
+	})
+}
+
+func (p *dmlProgress) emit(done bool) {
+	p.client.emit(Event{
+		Kind:           EventDMLProgress,
+		StatementIndex: int(p.completed.Load()),
+		StatementCount: p.statementCount,
+		RowsAffected:   p.rows.Load(),
+		Elapsed:        time.Since(p.start),
+		Done:           done,
+	})
+}
@@ -0,0 +1,53 @@
+package spanner
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_dmlProgress(t *testing.T) {
+	var mu sync.Mutex
+	var events []Event
+	client := &Client{config: &Config{EventHandler: func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}}}
+
+	progress := client.startDMLProgress(2)
+	progress.statementDone(10)
+	progress.addRows(5)
+	progress.statementDone(1)
+	progress.stop()
+	progress.stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, events, 4)
+	for _, e := range events {
+		assert.Equal(t, EventDMLProgress, e.Kind)
+		assert.Equal(t, 2, e.StatementCount)
+	}
+	assert.EqualValues(t, 10, events[0].RowsAffected)
+	assert.Equal(t, 1, events[0].StatementIndex)
+	assert.EqualValues(t, 15, events[1].RowsAffected)
+	assert.EqualValues(t, 16, events[2].RowsAffected)
+	assert.Equal(t, 2, events[2].StatementIndex)
+
+	last := events[3]
+	assert.True(t, last.Done)
+	assert.EqualValues(t, 16, last.RowsAffected)
+}
+
+func Test_dmlProgressWithoutHandler(t *testing.T) {
+	client := &Client{config: &Config{}}
+
+	progress := client.startDMLProgress(1)
+	progress.statementDone(1)
+	progress.stop()
+
+	assert.EqualValues(t, 1, progress.rows.Load())
+}
@@ -20,16 +20,20 @@
 package spanner
 
 import (
+	"cmp"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/spanner"
 	"google.golang.org/grpc/codes"
@@ -44,18 +48,36 @@ var (
 	// 001_name.sql
 	// 001_name.up.sql
 	// 001_name.generated.sql
-	migrationFileRegex = regexp.MustCompile(`^([0-9]+)(?:_([a-zA-Z0-9_\-]+))?(?:[.]up|[.]generated)?\.sql$`)
+	// 001_name.tmpl.sql
+	migrationFileRegex = regexp.MustCompile(`^([0-9]+)(?:_([a-zA-Z0-9_\-]+))?(?:[.]up|[.]generated|[.]tmpl)?\.sql$`)
 
 	// repeatableMigrationRegex matches the following patterns
 	// R__name.sql
 	// R__name.generated.sql
-	repeatableMigrationRegex = regexp.MustCompile(`(?i)^R__([a-zA-Z0-9_\-]+)(?:[.]generated)?\.sql$`)
+	// R__name.tmpl.sql
+	repeatableMigrationRegex = regexp.MustCompile(`(?i)^R__([a-zA-Z0-9_\-]+)(?:[.]generated|[.]tmpl)?\.sql$`)
+
+	// seedRegex matches the following patterns
+	// S__name.sql
+	// S__name.tmpl.sql
+	seedRegex = regexp.MustCompile(`(?i)^S__([a-zA-Z0-9_\-]+)(?:[.]tmpl)?\.sql$`)
+
+	// migrationSuffixRegex matches the suffix of a migration file that is replaced to find its companion files, e.g.
+	// 001_name.assert.sql for 001_name.up.sql
+	migrationSuffixRegex = regexp.MustCompile(`(?:[.]up|[.]generated|[.]tmpl)?\.sql$`)
+
+	// dependencyNameRegex matches the name of a repeatable migration in a DependsOn directive, with or without the
+	// prefix and suffix of the file name, e.g. R__name, R__name.sql or name
+	dependencyNameRegex = regexp.MustCompile(`(?i)^(?:[RS]__)?([a-zA-Z0-9_\-]+)(?:[.]generated|[.]tmpl)?(?:[.]sql)?$`)
+
+	// assertDirectiveRegex matches an assertion directive, where the query is the rest of the line
+	assertDirectiveRegex = regexp.MustCompile(`(?m)^\s*@wrench[.]Assert=(?P<Value>.+)$`)
 
 	MigrationNameRegex = regexp.MustCompile(`[a-zA-Z0-9_\-]+`)
 
 	dmlAnyRegex = regexp.MustCompile("^(UPDATE|DELETE|INSERT)[\t\n\f\r ].*")
 
-	placeholderRegex = regexp.MustCompile(`\$\{(?P<PlaceholderName>[A-Za-z_]+)\}`)
+	placeholderRegex = regexp.MustCompile(`\$\{(?P<PlaceholderName>[A-Za-z_][A-Za-z0-9_]*)(?::-(?P<Default>[^}]*))?\}`)
 
 	// 1. INSERT statements are not supported for partitioned DML. Although not every DML can be partitioned
 	// as it must be idempotent. This probably isn't solvable with more regexes.
@@ -72,6 +94,15 @@ const (
 	// in its own transaction, and the concurrency can be configured via the
 	// @wrench.Concurrency directive.
 	StatementKindConvergentDML StatementKind = "ConvergentDML"
+	// StatementKindBaseline is the kind of a baseline migration written by
+	// migrate squash. The DDL statements are applied in a single operation,
+	// followed by the DML statements that insert the static data.
+	StatementKindBaseline StatementKind = "Baseline"
+
+	// SkipReasonEnvironment is the SkipReason of migrations scoped to other environments.
+	SkipReasonEnvironment = "environment"
+	// SkipReasonEmulator is the SkipReason of migrations with the SkipOnEmulator directive on the emulator.
+	SkipReasonEmulator = "emulator"
 )
 
 type (
@@ -83,7 +114,10 @@ type (
 		// Name is the name of the migration
 		Name string
 
-		// FileName is the name of the source file for the migration
+		// Dir is the migrations directory the source file was loaded from.
+		Dir string
+
+		// FileName is the path of the source file for the migration, relative to Dir
 		FileName string
 
 		// Statements is the migration statements
@@ -96,7 +130,18 @@ type (
 
 		IsRepeatable bool
 
+		// IsSeed is set for seed data files, which are repeatable migrations tracked in their own history table.
+		IsSeed bool
+
 		Checksum string
+
+		// SkipReason is set if the migration is recorded in the history without being executed, e.g.
+		// SkipReasonEnvironment when the migration is scoped to other environments.
+		SkipReason string
+
+		// Assertions are queries run after the migration, from Assert directives and the companion assert file. The
+		// migration fails if an assertion returns any rows, unless it returns a single true boolean.
+		Assertions []string
 	}
 
 	// MigrationDirectives configures how the migration should be executed.
@@ -107,6 +152,34 @@ type (
 		// Kind defines the execution concurrency. Only applicable when
 		// StatementKind is StatementKindConvergentDML.
 		Concurrency int
+		// Retry is the number of times DML statements are retried after a
+		// transient error. Only set if the statements are safe to retry.
+		Retry int
+		// Timeout is the deadline for the DDL operation or DML statements of
+		// the migration to complete.
+		Timeout time.Duration
+		// Environments scopes the migration to the named environments. The
+		// migration is skipped in any other environment.
+		Environments []string
+		// SkipOnEmulator skips the migration when running against the
+		// emulator, for statements the emulator does not support.
+		SkipOnEmulator bool
+		// EmulatorReplacement is a file, relative to the directory of the
+		// migration file, executed instead of the migration on the emulator.
+		EmulatorReplacement string
+		// Assertions are queries run after the migration has executed.
+		Assertions []string
+		// DependsOn are the names of the repeatable migrations that this
+		// repeatable migration is executed after.
+		DependsOn []string
+		// RunAlways executes the repeatable migration on every migrate up,
+		// even if its checksum has not changed.
+		RunAlways bool
+		// Requires are the versions that must be applied before this
+		// versioned migration.
+		Requires []uint
+		// Squashed are the versions squashed into a baseline migration.
+		Squashed []uint
 	}
 
 	Migrations []*Migration
@@ -140,8 +213,160 @@ func (ms Migrations) Less(i, j int) bool {
 	return ms[i].Version < ms[j].Version
 }
 
+// OnEmulator returns true when connecting to the emulator, which is set by SPANNER_EMULATOR_HOST.
+func OnEmulator() bool {
+	return os.Getenv("SPANNER_EMULATOR_HOST") != ""
+}
+
+// applyEmulatorDirectives skips the migration, or replaces its statements with the emulator replacement file, which is
+// relative to the directory of the migration file. The checksum of the migration is unchanged so that repeatable migrations are not re-run when the replacement changes.
+func (m *Migration) applyEmulatorDirectives(detectPartitionedDML bool, placeholderOptions PlaceholderOptions) error {
+	if m.Directives.SkipOnEmulator {
+		m.SkipReason = SkipReasonEmulator
+		return nil
+	}
+
+	if m.Directives.EmulatorReplacement == "" {
+		return nil
+	}
+
+	dir := filepath.Join(m.Dir, filepath.FromSlash(path.Dir(m.FileName)))
+	file, err := os.ReadFile(filepath.Join(dir, m.Directives.EmulatorReplacement))
+	if err != nil {
+		return fmt.Errorf("migration %s: failed to read emulator replacement: %w", m.FileName, err)
+	}
+
+	statements, err := parseStatements(m.Directives.EmulatorReplacement, file, placeholderOptions)
+	if err != nil {
+		return err
+	}
+
+	kind, err := inspectStatementsKind(statements, detectPartitionedDML)
+	if err != nil {
+		return err
+	}
+
+	m.Statements = statements
+	m.Kind = kind
+	return nil
+}
+
+// MigrationVersion returns the version of a versioned migration file name or path, e.g. 120 for 2025/000120_name.sql.
+func MigrationVersion(fileName string) (uint, bool) {
+	matches := migrationFileRegex.FindStringSubmatch(path.Base(filepath.ToSlash(fileName)))
+	if matches == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(v), true
+}
+
+// AssertFileName returns the name of the companion assert file of a migration, e.g. 001_name.assert.sql for
+// 001_name.sql.
+func AssertFileName(fileName string) string {
+	return migrationSuffixRegex.ReplaceAllString(fileName, ".assert.sql")
+}
+
+// loadAssertions loads the queries of the companion assert file of a migration if it exists.
+func loadAssertions(dir, fileName string, placeholderOptions PlaceholderOptions) ([]string, error) {
+	assertFileName := AssertFileName(fileName)
+	file, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(assertFileName)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStatements(assertFileName, file, placeholderOptions)
+}
+
+// parseStatements renders the file if it is a template, then splits the file into statements and replaces any
+// placeholders.
+func parseStatements(fileName string, file []byte, placeholderOptions PlaceholderOptions) ([]string, error) {
+	if isTemplate(fileName) {
+		var err error
+		file, err = renderTemplate(fileName, file, placeholderOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	statements, err := toStatements(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if placeholderOptions.ReplacementEnabled {
+		statements, err = replacePlaceholders(statements, placeholderOptions.Placeholders)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+	}
+
+	return statements, nil
+}
+
+// SkipEnvironments sets the SkipReason of migrations that are scoped to environments other than the given environment.
+// If the environment is empty, repeatable migrations and seeds scoped to environments are skipped, but versioned
+// migrations are not as the skip would be recorded in the history for good, unless on the emulator. See
+// PendingEnvironmentScoped.
+func (ms Migrations) SkipEnvironments(environment string) {
+	for _, m := range ms {
+		if len(m.Directives.Environments) == 0 || slices.Contains(m.Directives.Environments, environment) {
+			continue
+		}
+		if environment == "" && !m.IsRepeatable && !OnEmulator() {
+			continue
+		}
+		m.SkipReason = SkipReasonEnvironment
+	}
+}
+
+// PendingEnvironmentScoped returns the versioned migrations scoped to environments that are not in the history. They
+// can only be applied or skipped once the environment is known.
+func (ms Migrations) PendingEnvironmentScoped(history []MigrationHistoryRecord) Migrations {
+	applied := make(map[int64]bool, len(history))
+	for _, h := range history {
+		applied[h.Version] = true
+	}
+
+	var pending Migrations
+	for _, m := range ms {
+		if !m.IsRepeatable && len(m.Directives.Environments) > 0 && !applied[int64(m.Version)] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// UsesPlaceholders returns true if the migration is a template or its statements contain placeholders, so that it may
+// be applied differently to each database.
+func (m *Migration) UsesPlaceholders() (bool, error) {
+	if isTemplate(m.FileName) {
+		return true, nil
+	}
+
+	file, err := os.ReadFile(MigrationFile{Dir: m.Dir, FileName: m.FileName}.Path())
+	if err != nil {
+		return false, err
+	}
+	statements, err := toStatements(file)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(statements, placeholderRegex.MatchString), nil
+}
+
+// LoadMigrations loads the versioned and repeatable migrations in dir and its subdirectories, except for archive
+// directories. dir may be a list of directories joined by os.PathListSeparator, and versions and repeatable names must
+// be unique across all of them. The FileName of each migration is its path relative to its migrations directory.
 func LoadMigrations(dir string, toSkipSlice []uint, detectPartitionedDML bool, placeholderOptions PlaceholderOptions) (Migrations, error) {
-	files, err := os.ReadDir(dir)
+	files, err := ListMigrationFiles(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -153,86 +378,63 @@ func LoadMigrations(dir string, toSkipSlice []uint, detectPartitionedDML bool, p
 
 	var migrations Migrations
 	for _, f := range files {
-		if f.IsDir() {
-			continue
-		}
-
 		var version uint64
 		var name string
 		var isRepeatable bool
 
-		if matches := migrationFileRegex.FindStringSubmatch(f.Name()); matches != nil {
+		baseName := path.Base(f.FileName)
+		if matches := migrationFileRegex.FindStringSubmatch(baseName); matches != nil {
 			v, err := strconv.ParseUint(matches[1], 10, 64)
 			if err != nil {
 				continue
 			}
 			if v == 0 {
-				return nil, fmt.Errorf("migration %s has invalid version 0; versioned migrations must start at 1", f.Name())
+				return nil, fmt.Errorf("migration %s has invalid version 0; versioned migrations must start at 1", f.FileName)
 			}
 			if toSkipMap[v] {
 				continue
 			}
 			version = v
 			name = matches[2]
-		} else if matches := repeatableMigrationRegex.FindStringSubmatch(f.Name()); matches != nil {
+		} else if matches := repeatableMigrationRegex.FindStringSubmatch(baseName); matches != nil {
 			isRepeatable = true
 			name = matches[1]
 		} else {
 			continue
 		}
 
-		file, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		file, err := os.ReadFile(f.Path())
 		if err != nil {
 			continue
 		}
 
-		statements, err := toStatements(file)
+		m, err := newMigration(f, file, detectPartitionedDML, placeholderOptions)
 		if err != nil {
 			return nil, err
 		}
-
-		if placeholderOptions.ReplacementEnabled {
-			statements, err = replacePlaceholders(statements, placeholderOptions.Placeholders)
-			if err != nil {
-				return nil, err
-			}
+		m.Version = uint(version)
+		m.Name = name
+		m.IsRepeatable = isRepeatable
+		if !isRepeatable && (len(m.Directives.DependsOn) > 0 || m.Directives.RunAlways) {
+			return nil, fmt.Errorf("migration %s: the DependsOn and RunAlways directives are only supported for repeatable migrations", f.FileName)
 		}
-
-		kind, err := inspectStatementsKind(statements, detectPartitionedDML)
-		if err != nil {
-			return nil, err
+		if isRepeatable && len(m.Directives.Requires) > 0 {
+			return nil, fmt.Errorf("migration %s: the Requires directive is only supported for versioned migrations", f.FileName)
 		}
-
-		// Parse any migration-scoped directives for the migration
-		directives, err := parseMigrationDirectives(string(file))
-		if err != nil {
-			return nil, err
+		if isRepeatable && m.Kind == StatementKindBaseline {
+			return nil, fmt.Errorf("migration %s: a baseline must be a versioned migration", f.FileName)
 		}
 
-		hash := sha256.New()
-		for _, stmt := range statements {
-			// Normalize line endings to LF to ensure consistent checksums across platforms
-			normalized := strings.ReplaceAll(stmt, "\r\n", "\n")
-			hash.Write([]byte(normalized))
-		}
-		checksum := hex.EncodeToString(hash.Sum(nil))
-
-		migrations = append(migrations, &Migration{
-			Version:      uint(version),
-			Name:         name,
-			FileName:     f.Name(),
-			Statements:   statements,
-			Kind:         kind,
-			Directives:   directives,
-			IsRepeatable: isRepeatable,
-			Checksum:     checksum,
-		})
+		migrations = append(migrations, m)
 	}
 
 	sort.Sort(migrations)
 	seen := map[uint]*Migration{}
 	seenRepeatable := map[string]*Migration{}
-	for _, m := range migrations {
+	for i, m := range migrations {
+		if i > 0 && m.Kind == StatementKindBaseline && !m.IsRepeatable {
+			return nil, fmt.Errorf("baseline migration %s must have the lowest version, but %s has a lower version", m.FileName, migrations[0].FileName)
+		}
 		if m.IsRepeatable {
 			if dupe, got := seenRepeatable[m.Name]; got {
 				return nil, fmt.Errorf("repeatable migration %s has a duplicate name in file %s", m.Name, dupe.FileName)
@@ -241,12 +443,168 @@ func LoadMigrations(dir string, toSkipSlice []uint, detectPartitionedDML bool, p
 			continue
 		}
 		if dupe, got := seen[m.Version]; got {
-			return nil, fmt.Errorf("migration %d %s has a duplicate version number of %s", m.Version, m.Name, dupe.Name)
+			return nil, fmt.Errorf("migration %d %s has a duplicate version number of %s in file %s", m.Version, m.Name, dupe.Name, dupe.FileName)
 		}
 		seen[m.Version] = m
 	}
 
-	return migrations, nil
+	return orderRepeatables(migrations)
+}
+
+// orderRepeatables orders the repeatable migrations after the versioned migrations, so that each repeatable migration
+// is after the migrations it depends on with the DependsOn directive, and otherwise by name.
+func orderRepeatables(ms Migrations) (Migrations, error) {
+	byName := map[string]*Migration{}
+	ordered := make(Migrations, 0, len(ms))
+	for _, m := range ms {
+		if m.IsRepeatable {
+			byName[m.Name] = m
+		} else {
+			ordered = append(ordered, m)
+		}
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(m *Migration) error
+	visit = func(m *Migration) error {
+		switch state[m.Name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, m.Name):]), m.Name)
+			return fmt.Errorf("repeatable migrations have a dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[m.Name] = visiting
+		path = append(path, m.Name)
+		for _, name := range m.Directives.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("migration %s depends on %s which does not exist", m.FileName, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[m.Name] = visited
+
+		ordered = append(ordered, m)
+		return nil
+	}
+
+	for _, m := range ms {
+		if m.IsRepeatable {
+			if err := visit(m); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+// LoadSeeds loads the seed data files in the directory and its subdirectories, e.g. S__users.sql. Seeds are applied like repeatable
+// migrations, whenever their checksum changes.
+func LoadSeeds(dir string, detectPartitionedDML bool, placeholderOptions PlaceholderOptions) (Migrations, error) {
+	files, err := ListMigrationFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var seeds Migrations
+	seen := map[string]*Migration{}
+	for _, f := range files {
+		matches := seedRegex.FindStringSubmatch(path.Base(f.FileName))
+		if matches == nil {
+			continue
+		}
+
+		file, err := os.ReadFile(f.Path())
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := newMigration(f, file, detectPartitionedDML, placeholderOptions)
+		if err != nil {
+			return nil, err
+		}
+		m.Name = matches[1]
+		m.IsRepeatable = true
+		m.IsSeed = true
+
+		if dupe, got := seen[m.Name]; got {
+			return nil, fmt.Errorf("seed %s has a duplicate name in file %s", m.Name, dupe.FileName)
+		}
+		seen[m.Name] = m
+		seeds = append(seeds, m)
+	}
+
+	sort.Sort(seeds)
+	return orderRepeatables(seeds)
+}
+
+// newMigration parses the statements and directives of a migration file. The caller sets the version, name and type of
+// the migration.
+func newMigration(f MigrationFile, file []byte, detectPartitionedDML bool, placeholderOptions PlaceholderOptions) (*Migration, error) {
+	fileName := f.FileName
+	statements, err := parseStatements(fileName, file, placeholderOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse any migration-scoped directives for the migration
+	directives, err := parseMigrationDirectives(string(file))
+	if err != nil {
+		return nil, err
+	}
+
+	// a baseline migration has both DDL and DML statements
+	kind := StatementKindBaseline
+	if directives.StatementKind != StatementKindBaseline {
+		kind, err = inspectStatementsKind(statements, detectPartitionedDML)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if directives.Retry > 0 && cmp.Or(directives.StatementKind, kind) == StatementKindDDL {
+		return nil, fmt.Errorf("migration %s: the Retry directive is only supported for DML", fileName)
+	}
+
+	hash := sha256.New()
+	for _, stmt := range statements {
+		// Normalize line endings to LF to ensure consistent checksums across platforms
+		normalized := strings.ReplaceAll(stmt, "\r\n", "\n")
+		hash.Write([]byte(normalized))
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	assertions, err := loadAssertions(f.Dir, fileName, placeholderOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migration{
+		Dir:        f.Dir,
+		FileName:   fileName,
+		Statements: statements,
+		Kind:       kind,
+		Directives: directives,
+		Checksum:   checksum,
+		Assertions: append(slices.Clone(directives.Assertions), assertions...),
+	}
+	if OnEmulator() {
+		if err := m.applyEmulatorDirectives(detectPartitionedDML, placeholderOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
 }
 
 // toStatements parses a migration file into a slice of statements by splitting
@@ -387,23 +745,28 @@ func toStatements(file []byte) ([]string, error) {
 	return statements, nil
 }
 
+// replacePlaceholders replaces ${NAME} with the value of the placeholder, or ${NAME:-default} with the default if the
+// placeholder is not defined.
 func replacePlaceholders(statements []string, placeholders map[string]string) ([]string, error) {
 	replacedStatements := []string{}
 	for _, statement := range statements {
-		// Check for any placeholder references that are not configured in the placeholders key value map.
-		placeholdersInStatement, found := xregexp.FindAllMatchGroups(placeholderRegex, statement)
-		if found {
-			for _, match := range placeholdersInStatement {
-				_, ok := placeholders[match["PlaceholderName"]]
-				if !ok {
-					return nil, fmt.Errorf("migration statement refers to placeholder variable that is not defined")
-				}
+		var err error
+		statement = placeholderRegex.ReplaceAllStringFunc(statement, func(s string) string {
+			match := placeholderRegex.FindStringSubmatch(s)
+			name := match[placeholderRegex.SubexpIndex("PlaceholderName")]
+			if value, ok := placeholders[name]; ok {
+				return value
 			}
-		}
-
-		// Perform placeholder replacement.
-		for placeholderName, placeholderValue := range placeholders {
-			statement = strings.ReplaceAll(statement, fmt.Sprintf("${%s}", placeholderName), placeholderValue)
+			if strings.Contains(s, ":-") {
+				return match[placeholderRegex.SubexpIndex("Default")]
+			}
+			if err == nil {
+				err = fmt.Errorf("migration statement refers to placeholder ${%s} that is not defined", name)
+			}
+			return s
+		})
+		if err != nil {
+			return nil, err
 		}
 		replacedStatements = append(replacedStatements, statement)
 	}
@@ -594,15 +957,29 @@ func parseMigrationDirectives(migration string) (MigrationDirectives, error) {
 	const (
 		concurrencyKey   = "Concurrency"
 		statementKindKey = "StatementKind"
+		retryKey         = "Retry"
+		timeoutKey       = "Timeout"
+		environmentsKey  = "Environments"
+		skipOnEmulator   = "SkipOnEmulator"
+		emulatorReplace  = "EmulatorReplacement"
+		assertKey        = "Assert"
+		dependsOnKey     = "DependsOn"
+		runAlwaysKey     = "RunAlways"
+		requiresKey      = "Requires"
+		squashedKey      = "Squashed"
 	)
 
-	// matches a migration directive in the format @wrench.{key}={value}
-	directiveRegex := regexp.MustCompile(`(?m)^\s*@wrench[.](?P<Key>\w+)=(?P<Value>\w+)`)
+	// matches a migration directive in the format @wrench.{key}={value}, or @wrench.{key} for flags
+	directiveRegex := regexp.MustCompile(`(?m)^\s*@wrench[.](?P<Key>\w+)(?:=(?P<Value>[\w.,\-/]+))?`)
 	directiveMatches, _ := xregexp.FindAllMatchGroups(directiveRegex, extractPreamble(migration))
 
 	var directives MigrationDirectives
 	for _, match := range directiveMatches {
 		key, val := match["Key"], match["Value"]
+		if val == "" && key != skipOnEmulator && key != assertKey && key != runAlwaysKey {
+			return MigrationDirectives{}, fmt.Errorf("missing value for migration directive: %s", key)
+		}
+
 		switch key {
 		case statementKindKey:
 			directives.StatementKind = StatementKind(val)
@@ -612,11 +989,74 @@ func parseMigrationDirectives(migration string) (MigrationDirectives, error) {
 				return MigrationDirectives{}, fmt.Errorf("invalid concurrency value: %s", val)
 			}
 			directives.Concurrency = concurrency
+		case retryKey:
+			retry, err := strconv.Atoi(val)
+			if err != nil || retry < 0 {
+				return MigrationDirectives{}, fmt.Errorf("invalid retry value: %s", val)
+			}
+			directives.Retry = retry
+		case timeoutKey:
+			timeout, err := time.ParseDuration(val)
+			if err != nil || timeout <= 0 {
+				return MigrationDirectives{}, fmt.Errorf("invalid timeout value: %s", val)
+			}
+			directives.Timeout = timeout
+		case environmentsKey:
+			for _, env := range strings.Split(val, ",") {
+				if env == "" {
+					return MigrationDirectives{}, fmt.Errorf("invalid environments value: %s", val)
+				}
+				directives.Environments = append(directives.Environments, env)
+			}
+		case skipOnEmulator:
+			if val != "" {
+				return MigrationDirectives{}, fmt.Errorf("unexpected value for %s: %s", key, val)
+			}
+			directives.SkipOnEmulator = true
+		case emulatorReplace:
+			directives.EmulatorReplacement = val
+		case assertKey:
+			// parsed by assertDirectiveRegex as the query is the rest of the line
+		case dependsOnKey:
+			for _, dep := range strings.Split(val, ",") {
+				matches := dependencyNameRegex.FindStringSubmatch(dep)
+				if matches == nil {
+					return MigrationDirectives{}, fmt.Errorf("invalid depends on value: %s", val)
+				}
+				directives.DependsOn = append(directives.DependsOn, matches[1])
+			}
+		case requiresKey:
+			for _, v := range strings.Split(val, ",") {
+				version, err := strconv.ParseUint(v, 10, 64)
+				if err != nil || version == 0 {
+					return MigrationDirectives{}, fmt.Errorf("invalid requires value: %s", val)
+				}
+				directives.Requires = append(directives.Requires, uint(version))
+			}
+		case squashedKey:
+			versions, err := parseVersionRanges(val)
+			if err != nil {
+				return MigrationDirectives{}, fmt.Errorf("invalid squashed value: %s", val)
+			}
+			directives.Squashed = versions
+		case runAlwaysKey:
+			if val != "" {
+				return MigrationDirectives{}, fmt.Errorf("unexpected value for %s: %s", key, val)
+			}
+			directives.RunAlways = true
 		default:
 			return directives, fmt.Errorf("unknown migration directive: %s", key)
 		}
 	}
 
+	assertMatches, _ := xregexp.FindAllMatchGroups(assertDirectiveRegex, extractPreamble(migration))
+	for _, match := range assertMatches {
+		// the closing */ of a block comment is not part of the query, like the trailing semicolon
+		sql := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(match["Value"]), "*/"))
+		sql = strings.TrimSuffix(sql, ";")
+		directives.Assertions = append(directives.Assertions, sql)
+	}
+
 	return directives, nil
 }
 
@@ -24,6 +24,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -95,6 +96,108 @@ func TestLoadMigrationsSkipVersion(t *testing.T) {
 	}
 }
 
+func TestLoadMigrationsOnEmulator(t *testing.T) {
+	dir := filepath.Join("testdata", "emulator")
+
+	t.Run("NotEmulator", func(t *testing.T) {
+		t.Setenv("SPANNER_EMULATOR_HOST", "")
+		ms, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+		require.NoError(t, err)
+		require.Len(t, ms, 3)
+
+		assert.Empty(t, ms[1].SkipReason)
+		assert.Equal(t, []string{"CREATE SEARCH INDEX SingersByFirstName ON Singers(FirstNameTokens)"}, ms[2].Statements)
+	})
+
+	t.Run("Emulator", func(t *testing.T) {
+		t.Setenv("SPANNER_EMULATOR_HOST", "localhost:9010")
+		ms, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+		require.NoError(t, err)
+		require.Len(t, ms, 3)
+
+		assert.Empty(t, ms[0].SkipReason)
+		assert.Equal(t, SkipReasonEmulator, ms[1].SkipReason)
+		assert.Equal(t, []string{"CREATE INDEX SingersByFirstName ON Singers(FirstName)"}, ms[2].Statements)
+		assert.Equal(t, StatementKindDDL, ms[2].Kind)
+	})
+}
+
+func TestLoadMigrationsAssertions(t *testing.T) {
+	ms, err := LoadMigrations(filepath.Join("testdata", "assert"), nil, false, PlaceholderOptions{})
+	require.NoError(t, err)
+	require.Len(t, ms, 2)
+
+	assert.Equal(t, []string{
+		"SELECT COUNT(*) = 0 FROM Singers WHERE FirstName IS NULL",
+		"SELECT SingerID FROM Singers WHERE FirstName IS NULL",
+		"SELECT COUNT(*) > 0 FROM Singers",
+	}, ms[0].Assertions)
+	assert.Empty(t, ms[1].Assertions)
+}
+
+func TestLoadMigrationsTemplates(t *testing.T) {
+	dir := filepath.Join("testdata", "templates")
+
+	single, err := LoadMigrations(dir, nil, false, PlaceholderOptions{
+		Placeholders:       map[string]string{"SHARDS": "2", "MULTI_REGION": "false"},
+		ReplacementEnabled: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, single, 2)
+	assert.Equal(t, "shard_indexes", single[0].Name)
+	assert.Equal(t, []string{
+		"CREATE INDEX Orders_1_ByCustomer ON Orders_1(CustomerID)",
+		"CREATE INDEX Orders_2_ByCustomer ON Orders_2(CustomerID)",
+	}, single[0].Statements)
+
+	multi, err := LoadMigrations(dir, nil, false, PlaceholderOptions{
+		Placeholders:       map[string]string{"SHARDS": "2", "MULTI_REGION": "true", "LEADER": "us-east1"},
+		ReplacementEnabled: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER DATABASE db SET OPTIONS (default_leader = 'us-east1')", multi[0].Statements[2])
+
+	// the checksum is of the rendered statements
+	assert.NotEqual(t, single[0].Checksum, multi[0].Checksum)
+
+	_, err = LoadMigrations(dir, nil, false, PlaceholderOptions{ReplacementEnabled: true})
+	assert.ErrorContains(t, err, "000001_shard_indexes.tmpl.sql")
+}
+
+func TestLoadSeeds(t *testing.T) {
+	dir := filepath.Join("testdata", "seeds")
+
+	seeds, err := LoadSeeds(dir, false, PlaceholderOptions{ReplacementEnabled: true})
+	require.NoError(t, err)
+	require.Len(t, seeds, 2)
+	assert.Equal(t, "countries", seeds[0].Name)
+	assert.Equal(t, "dev_singers", seeds[1].Name)
+	for _, s := range seeds {
+		assert.True(t, s.IsSeed)
+		assert.True(t, s.IsRepeatable)
+		assert.Equal(t, StatementKindDML, s.Kind)
+	}
+	assert.Equal(t, []string{"INSERT OR UPDATE INTO Singers (SingerID, FirstName) VALUES ('1', 'Country')"}, seeds[0].Statements)
+
+	seeds.SkipEnvironments("prod")
+	assert.Empty(t, seeds[0].SkipReason)
+	assert.Equal(t, SkipReasonEnvironment, seeds[1].SkipReason)
+
+	// seeds are not migrations
+	ms, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+	require.NoError(t, err)
+	require.Len(t, ms, 1)
+	assert.Equal(t, "create_singers", ms[0].Name)
+}
+
+func TestLoadMigrationsRepeatableDirectivesOnVersioned(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000001_backfill.sql"), []byte("-- @wrench.RunAlways\nUPDATE Singers SET FirstName = 'a' WHERE true;"), 0o644))
+
+	_, err := LoadMigrations(dir, nil, false, PlaceholderOptions{})
+	assert.ErrorContains(t, err, "only supported for repeatable migrations")
+}
+
 func TestLoadMigrationsDuplicates(t *testing.T) {
 	ms, err := LoadMigrations(filepath.Join("testdata", "duplicate"), nil, false, PlaceholderOptions{})
 	if err == nil {
@@ -105,6 +208,37 @@ func TestLoadMigrationsDuplicates(t *testing.T) {
 	}
 }
 
+func TestLoadMigrationsSubdirectories(t *testing.T) {
+	dir := t.TempDir()
+	shared := t.TempDir()
+	for _, d := range []string{"2025", "2026", "repeatables", ArchiveDirName} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, d), os.ModePerm))
+	}
+	newFile(t, dir, "000100_init.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "2025/000110_a.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "2025/000110_a.assert.sql", []byte("SELECT true;"))
+	newFile(t, dir, "2026/000120_b.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "repeatables/R__view.sql", []byte("SELECT 1;"))
+	newFile(t, dir, ArchiveDirName+"/000010_old.sql", []byte("SELECT 1;"))
+	newFile(t, shared, "000115_shared.sql", []byte("SELECT 1;"))
+
+	ms, err := LoadMigrations(dir+string(os.PathListSeparator)+shared, nil, false, PlaceholderOptions{})
+	require.NoError(t, err)
+
+	var fileNames []string
+	for _, m := range ms {
+		fileNames = append(fileNames, m.FileName)
+	}
+	assert.Equal(t, []string{"000100_init.sql", "2025/000110_a.sql", "000115_shared.sql", "2026/000120_b.sql", "repeatables/R__view.sql"}, fileNames)
+	assert.Equal(t, shared, ms[2].Dir)
+	assert.Equal(t, []string{"SELECT true"}, ms[1].Assertions)
+
+	// versions must be unique across all directories
+	newFile(t, shared, "000120_c.sql", []byte("SELECT 1;"))
+	_, err = LoadMigrations(dir+string(os.PathListSeparator)+shared, nil, false, PlaceholderOptions{})
+	assert.ErrorContains(t, err, "duplicate version number of b in file 2026/000120_b.sql")
+}
+
 func TestLoadMigrationsSubstitutePlaceHolders(t *testing.T) {
 	placeholderOptions := PlaceholderOptions{
 		Placeholders:       TestPlaceholders,
@@ -120,6 +254,16 @@ func TestLoadMigrationsSubstitutePlaceHolders(t *testing.T) {
 	}
 }
 
+func TestLoadMigrationsUndefinedPlaceholder(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000001_grant.sql"), []byte("GRANT SELECT ON TABLE Singers TO ROLE ${READER_ROLE};"), 0o644))
+
+	_, err := LoadMigrations(dir, nil, false, PlaceholderOptions{Placeholders: TestPlaceholders, ReplacementEnabled: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "000001_grant.sql")
+	assert.Contains(t, err.Error(), "${READER_ROLE}")
+}
+
 func TestReplacePlaceholders(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -174,6 +318,28 @@ func TestReplacePlaceholders(t *testing.T) {
 
 			wantErr: true,
 		},
+		{
+			name: "replaces placeholders with digits in the name",
+			statements: []string{
+				`ALTER DATABASE db SET OPTIONS (version_retention_period = "${RETENTION_DAYS_V2}d");`,
+			},
+			placeholders: map[string]string{"RETENTION_DAYS_V2": "7"},
+			want: []string{
+				`ALTER DATABASE db SET OPTIONS (version_retention_period = "7d");`,
+			},
+		},
+		{
+			name: "uses the default when the placeholder is not configured",
+			statements: []string{
+				`GRANT SELECT ON TABLE Singers TO ROLE ${READER_ROLE:-reader};`,
+				`UPDATE Singers SET FirstName = "${FIRST_NAME:-}" WHERE SingerID = "${DATABASE_ID:-unused}";`,
+			},
+			placeholders: TestPlaceholders,
+			want: []string{
+				`GRANT SELECT ON TABLE Singers TO ROLE reader;`,
+				`UPDATE Singers SET FirstName = "" WHERE SingerID = "databaseID789";`,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -366,6 +532,10 @@ func Test_migrationFileRegex(t *testing.T) {
 			input:    "001_name.generated.sql",
 			expected: []string{"001_name.generated.sql", "001", "name"},
 		},
+		"MatchAndIgnoreTemplate": {
+			input:    "001_name.tmpl.sql",
+			expected: []string{"001_name.tmpl.sql", "001", "name"},
+		},
 		"NotMatchDownMigration": {
 			input:    "001_name.down.sql",
 			expected: nil,
@@ -401,6 +571,10 @@ func Test_repeatableMigrationRegex(t *testing.T) {
 			input:    "R__000010_my-name.generated.sql",
 			expected: []string{"R__000010_my-name.generated.sql", "000010_my-name"},
 		},
+		"TemplateSuffix": {
+			input:    "R__my-view.tmpl.sql",
+			expected: []string{"R__my-view.tmpl.sql", "my-view"},
+		},
 		"NoMatchVersioned": {
 			input:    "001_name.sql",
 			expected: nil,
@@ -878,6 +1052,104 @@ SELECT 1 FROM Foo`, testStatementKind),
 				Concurrency:   123,
 			},
 		},
+		{
+			name: "PreambleWithDirectives_Retry",
+			data: `
+-- @wrench.Retry=3
+UPDATE Foo SET Bar = 1 WHERE true`,
+			want: MigrationDirectives{
+				Retry: 3,
+			},
+		},
+		{
+			name: "PreambleWithDirectives_Timeout",
+			data: `
+-- @wrench.Timeout=1h30m
+-- @wrench.Retry=1
+UPDATE Foo SET Bar = 1 WHERE true`,
+			want: MigrationDirectives{
+				Retry:   1,
+				Timeout: 90 * time.Minute,
+			},
+		},
+		{
+			name: "PreambleWithDirectives_FractionalTimeout",
+			data: `
+/* @wrench.Timeout=1.5s */
+CREATE INDEX FooBar ON Foo(Bar)`,
+			want: MigrationDirectives{
+				Timeout: 1500 * time.Millisecond,
+			},
+		},
+		{
+			name: "PreambleWithDirectives_Environments",
+			data: `
+-- @wrench.Environments=dev,staging-eu
+INSERT INTO Foo (Bar) VALUES (1)`,
+			want: MigrationDirectives{
+				Environments: []string{"dev", "staging-eu"},
+			},
+		},
+		{
+			name: "PreambleWithDirectives_Emulator",
+			data: `
+/*
+ @wrench.SkipOnEmulator
+ @wrench.EmulatorReplacement=emulator/000003_create_index.sql
+*/
+CREATE INDEX FooBar ON Foo(Bar)`,
+			want: MigrationDirectives{
+				SkipOnEmulator:      true,
+				EmulatorReplacement: "emulator/000003_create_index.sql",
+			},
+		},
+		{
+			name: "PreambleWithDirectives_Assert",
+			data: `
+-- @wrench.Assert=(SELECT COUNT(*) FROM Foo WHERE Bar IS NULL) = 0;
+-- @wrench.Assert=SELECT Baz FROM Foo WHERE Bar < 0
+UPDATE Foo SET Bar = 1 WHERE true`,
+			want: MigrationDirectives{
+				Assertions: []string{
+					"(SELECT COUNT(*) FROM Foo WHERE Bar IS NULL) = 0",
+					"SELECT Baz FROM Foo WHERE Bar < 0",
+				},
+			},
+		},
+		{
+			name: "PreambleWithDirectives_AssertBlockComment",
+			data: `
+/* @wrench.Assert=SELECT COUNT(*) = 0 FROM Foo WHERE Bar IS NULL; */
+/* @wrench.Assert=SELECT Baz FROM Foo WHERE Bar < 0*/
+UPDATE Foo SET Bar = 1 WHERE true`,
+			want: MigrationDirectives{
+				Assertions: []string{
+					"SELECT COUNT(*) = 0 FROM Foo WHERE Bar IS NULL",
+					"SELECT Baz FROM Foo WHERE Bar < 0",
+				},
+			},
+		},
+		{
+			name: "PreambleWithDirectives_Repeatable",
+			data: `
+-- @wrench.DependsOn=R__base_view,other_view.sql
+-- @wrench.DependsOn=R__function.tmpl.sql
+-- @wrench.RunAlways
+CREATE OR REPLACE VIEW Foo SQL SECURITY INVOKER AS SELECT 1 AS Bar`,
+			want: MigrationDirectives{
+				DependsOn: []string{"base_view", "other_view", "function"},
+				RunAlways: true,
+			},
+		},
+		{
+			name: "PreambleWithDirectives_Requires",
+			data: `
+-- @wrench.Requires=000120,130
+ALTER TABLE Foo ADD COLUMN Baz INT64`,
+			want: MigrationDirectives{
+				Requires: []uint{120, 130},
+			},
+		},
 		{
 			name: "PreambleWithDirectives_LineComment",
 			data: fmt.Sprintf(`
@@ -953,6 +1225,51 @@ SELECT 1 FROM Foo
 			assert.Error(t, err)
 		})
 
+		t.Run("InvalidRetry", func(t *testing.T) {
+			got, err := parseMigrationDirectives(`
+-- @wrench.Retry=abc
+UPDATE Foo SET Bar = 1 WHERE true
+`)
+			assert.Zero(t, got)
+			assert.Error(t, err)
+		})
+
+		t.Run("InvalidTimeout", func(t *testing.T) {
+			got, err := parseMigrationDirectives(`
+-- @wrench.Timeout=45
+UPDATE Foo SET Bar = 1 WHERE true
+`)
+			assert.Zero(t, got)
+			assert.Error(t, err)
+		})
+
+		t.Run("MissingValue", func(t *testing.T) {
+			got, err := parseMigrationDirectives(`
+-- @wrench.Timeout
+UPDATE Foo SET Bar = 1 WHERE true
+`)
+			assert.Zero(t, got)
+			assert.Error(t, err)
+		})
+
+		t.Run("InvalidDependsOn", func(t *testing.T) {
+			got, err := parseMigrationDirectives(`
+-- @wrench.DependsOn=R__base,,R__other
+CREATE OR REPLACE VIEW Foo SQL SECURITY INVOKER AS SELECT 1 AS Bar
+`)
+			assert.Zero(t, got)
+			assert.Error(t, err)
+		})
+
+		t.Run("InvalidRequires", func(t *testing.T) {
+			got, err := parseMigrationDirectives(`
+-- @wrench.Requires=abc
+ALTER TABLE Foo ADD COLUMN Baz INT64
+`)
+			assert.Zero(t, got)
+			assert.Error(t, err)
+		})
+
 		t.Run("UnknownKey", func(t *testing.T) {
 			got, err := parseMigrationDirectives(`
 -- @wrench.foo=bar
@@ -964,6 +1281,104 @@ SELECT 1 FROM Foo
 	})
 }
 
+func Test_orderRepeatables(t *testing.T) {
+	repeatable := func(name string, dependsOn ...string) *Migration {
+		return &Migration{Name: name, FileName: "R__" + name + ".sql", IsRepeatable: true, Directives: MigrationDirectives{DependsOn: dependsOn}}
+	}
+	names := func(ms Migrations) []string {
+		var names []string
+		for _, m := range ms {
+			if m.IsRepeatable {
+				names = append(names, m.Name)
+			} else {
+				names = append(names, fmt.Sprint(m.Version))
+			}
+		}
+		return names
+	}
+
+	t.Run("DependenciesFirst", func(t *testing.T) {
+		got, err := orderRepeatables(Migrations{
+			{Version: 1},
+			repeatable("a_view", "c_function"),
+			repeatable("b_view", "a_view"),
+			repeatable("c_function"),
+			repeatable("d_view"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", "c_function", "a_view", "b_view", "d_view"}, names(got))
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		_, err := orderRepeatables(Migrations{
+			repeatable("a", "b"),
+			repeatable("b", "c"),
+			repeatable("c", "a"),
+		})
+		assert.EqualError(t, err, "repeatable migrations have a dependency cycle: a -> b -> c -> a")
+	})
+
+	t.Run("MissingDependency", func(t *testing.T) {
+		_, err := orderRepeatables(Migrations{
+			repeatable("a", "missing"),
+		})
+		assert.EqualError(t, err, "migration R__a.sql depends on missing which does not exist")
+	})
+}
+
+func TestMigrations_PendingEnvironmentScoped(t *testing.T) {
+	ms := Migrations{
+		{Version: 1},
+		{Version: 2, Directives: MigrationDirectives{Environments: []string{"dev"}}},
+		{Version: 3, Directives: MigrationDirectives{Environments: []string{"prod"}}},
+		{Name: "view", IsRepeatable: true, Directives: MigrationDirectives{Environments: []string{"prod"}}},
+	}
+
+	pending := ms.PendingEnvironmentScoped([]MigrationHistoryRecord{{Version: 1}, {Version: 2}})
+	require.Len(t, pending, 1)
+	assert.Equal(t, uint(3), pending[0].Version)
+
+	assert.Empty(t, ms.PendingEnvironmentScoped([]MigrationHistoryRecord{{Version: 1}, {Version: 2}, {Version: 3}}))
+}
+
+func TestMigrations_SkipEnvironments(t *testing.T) {
+	// versioned migrations are skipped without an environment on the emulator
+	t.Setenv("SPANNER_EMULATOR_HOST", "")
+
+	newMigrations := func() Migrations {
+		return Migrations{
+			{Version: 1},
+			{Version: 2, Directives: MigrationDirectives{Environments: []string{"dev", "staging"}}},
+			{Version: 3, Directives: MigrationDirectives{Environments: []string{"prod"}}},
+			{Name: "view", IsRepeatable: true, Directives: MigrationDirectives{Environments: []string{"prod"}}},
+		}
+	}
+	skipReasons := func(ms Migrations) []string {
+		var reasons []string
+		for _, m := range ms {
+			reasons = append(reasons, m.SkipReason)
+		}
+		return reasons
+	}
+
+	tests := []struct {
+		environment string
+		want        []string
+	}{
+		{environment: "dev", want: []string{"", "", SkipReasonEnvironment, SkipReasonEnvironment}},
+		{environment: "prod", want: []string{"", SkipReasonEnvironment, "", ""}},
+		// versioned migrations are not recorded as skipped without an environment
+		{environment: "", want: []string{"", "", "", SkipReasonEnvironment}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			ms := newMigrations()
+			ms.SkipEnvironments(tt.environment)
+			assert.Equal(t, tt.want, skipReasons(ms))
+		})
+	}
+}
+
 func Test_extractPreamble(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1133,3 +1548,26 @@ func TestLoadMigrationsRepeatableLineEndings(t *testing.T) {
 
 	assert.Equal(t, msLF[0].Checksum, msCRLF[0].Checksum, "checksums should be equal regardless of line endings")
 }
+
+func TestMigrationUsesPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"000001_plain.sql":         "-- ${NOT_A_PLACEHOLDER} in a comment\nCREATE TABLE Singers (ID INT64) PRIMARY KEY(ID);\n",
+		"000002_placeholder.sql":   "CREATE TABLE Albums (ID INT64, Region STRING(MAX) DEFAULT ('${REGION:-us}')) PRIMARY KEY(ID);\n",
+		"000003_template.tmpl.sql": "CREATE TABLE Songs (ID INT64) PRIMARY KEY(ID);\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	ms, err := LoadMigrations(dir, nil, false, PlaceholderOptions{ReplacementEnabled: true})
+	require.NoError(t, err)
+	require.Len(t, ms, 3)
+
+	want := map[string]bool{"000001_plain.sql": false, "000002_placeholder.sql": true, "000003_template.tmpl.sql": true}
+	for _, m := range ms {
+		got, err := m.UsesPlaceholders()
+		require.NoError(t, err)
+		assert.Equal(t, want[m.FileName], got, m.FileName)
+	}
+}
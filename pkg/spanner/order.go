@@ -0,0 +1,131 @@
+package spanner
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// OutOfOrderPolicy is how pending migrations with a lower version than an applied migration are handled, e.g. a hotfix
+// inserted between applied versions.
+type OutOfOrderPolicy string
+
+const (
+	// OutOfOrderAllow applies out-of-order migrations. This is the default.
+	OutOfOrderAllow OutOfOrderPolicy = "allow"
+	// OutOfOrderWarn applies out-of-order migrations after printing a warning.
+	OutOfOrderWarn OutOfOrderPolicy = "warn"
+	// OutOfOrderDeny fails before applying any migration if there are out-of-order migrations.
+	OutOfOrderDeny OutOfOrderPolicy = "deny"
+)
+
+// ParseOutOfOrderPolicy parses allow, warn or deny.
+func ParseOutOfOrderPolicy(s string) (OutOfOrderPolicy, error) {
+	switch p := OutOfOrderPolicy(s); p {
+	case OutOfOrderAllow, OutOfOrderWarn, OutOfOrderDeny:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid out-of-order policy %q, must be one of allow, warn or deny", s)
+	}
+}
+
+// checkOutOfOrder applies the policy to the pending migrations with a lower version than the latest applied version.
+func checkOutOfOrder(migrations Migrations, history []MigrationHistoryRecord, policy OutOfOrderPolicy) error {
+	if policy == "" || policy == OutOfOrderAllow || len(history) == 0 {
+		return nil
+	}
+
+	var latest int64
+	applied := make(map[int64]bool, len(history))
+	for _, h := range history {
+		applied[h.Version] = true
+		latest = max(latest, h.Version)
+	}
+
+	var outOfOrder []string
+	for _, m := range migrations {
+		if !m.IsRepeatable && !applied[int64(m.Version)] && int64(m.Version) < latest {
+			outOfOrder = append(outOfOrder, m.FileName)
+		}
+	}
+	if len(outOfOrder) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("out-of-order migrations have a lower version than the applied version %d: %s", latest, strings.Join(outOfOrder, ", "))
+	if policy == OutOfOrderWarn {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+		return nil
+	}
+
+	return &Error{
+		Code: ErrorCodeMigrationOrder,
+		err:  fmt.Errorf("%s. Renumber the migrations or run with --out-of-order=allow", msg),
+	}
+}
+
+// checkRequires returns an error if a pending migration requires a version, with the Requires directive, that is not
+// applied before it. Versions are applied in order, so a pending required version is satisfied if it is lower than the
+// migration and is not itself blocked. The error shows the chain of requirements that leads to the missing version.
+func checkRequires(migrations Migrations, history []MigrationHistoryRecord) error {
+	applied := make(map[int64]bool, len(history))
+	skipped := make(map[int64]string)
+	for _, h := range history {
+		applied[h.Version] = true
+		if h.SkipReason.Valid {
+			skipped[h.Version] = h.SkipReason.StringVal
+		}
+	}
+
+	byVersion := make(map[uint]*Migration, len(migrations))
+	for _, m := range migrations {
+		if !m.IsRepeatable {
+			byVersion[m.Version] = m
+		}
+	}
+
+	// blocked returns the requirement chain from m to the first version that cannot be satisfied, or nil
+	var blocked func(m *Migration, chain []string) []string
+	blocked = func(m *Migration, chain []string) []string {
+		chain = append(chain, m.FileName)
+		for _, v := range m.Directives.Requires {
+			if reason, ok := skipped[int64(v)]; ok {
+				return append(chain, fmt.Sprintf("version %d, which was skipped (%s)", v, reason))
+			}
+			if applied[int64(v)] {
+				continue
+			}
+			required, ok := byVersion[v]
+			switch {
+			case !ok:
+				return append(chain, fmt.Sprintf("version %d, which has not been applied and has no migration file", v))
+			case required.SkipReason != "":
+				return append(chain, fmt.Sprintf("%s, which will be skipped (%s)", required.FileName, required.SkipReason))
+			case v > m.Version:
+				return append(chain, fmt.Sprintf("%s, which has a higher version and has not been applied", required.FileName))
+			case slices.Contains(chain, required.FileName):
+				return append(chain, required.FileName+", which is a cycle")
+			}
+			if c := blocked(required, slices.Clone(chain)); c != nil {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// check the highest versions first, so that the error shows the longest chain
+	for _, m := range slices.Backward(migrations) {
+		if m.IsRepeatable || applied[int64(m.Version)] || m.SkipReason != "" {
+			continue
+		}
+		if chain := blocked(m, nil); chain != nil {
+			return &Error{
+				Code: ErrorCodeMigrationOrder,
+				err:  fmt.Errorf("migration %s is blocked: %s", m.FileName, strings.Join(chain, " requires ")),
+			}
+		}
+	}
+
+	return nil
+}
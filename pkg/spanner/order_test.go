@@ -0,0 +1,95 @@
+package spanner
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkOutOfOrder(t *testing.T) {
+	migrations := Migrations{
+		{Version: 10, FileName: "000010.sql"},
+		{Version: 15, FileName: "000015_hotfix.sql"},
+		{Version: 20, FileName: "000020.sql"},
+		{Version: 30, FileName: "000030.sql"},
+		{Name: "view", FileName: "R__view.sql", IsRepeatable: true},
+	}
+	history := []MigrationHistoryRecord{{Version: 10}, {Version: 20}}
+
+	assert.NoError(t, checkOutOfOrder(migrations, history, OutOfOrderAllow))
+	assert.NoError(t, checkOutOfOrder(migrations, history, ""))
+	assert.NoError(t, checkOutOfOrder(migrations, history, OutOfOrderWarn))
+	assert.NoError(t, checkOutOfOrder(migrations, nil, OutOfOrderDeny))
+
+	err := checkOutOfOrder(migrations, history, OutOfOrderDeny)
+	assert.ErrorContains(t, err, "lower version than the applied version 20: 000015_hotfix.sql")
+}
+
+func Test_checkRequires(t *testing.T) {
+	requires := func(v uint, fileName string, requires ...uint) *Migration {
+		return &Migration{Version: v, FileName: fileName, Directives: MigrationDirectives{Requires: requires}}
+	}
+
+	tests := map[string]struct {
+		migrations Migrations
+		history    []MigrationHistoryRecord
+		wantErr    string
+	}{
+		"applied": {
+			migrations: Migrations{requires(10, "000010.sql"), requires(15, "000015.sql", 10)},
+			history:    []MigrationHistoryRecord{{Version: 10}},
+		},
+		"pending lower version": {
+			migrations: Migrations{requires(10, "000010.sql"), requires(15, "000015.sql", 10)},
+		},
+		"higher version": {
+			migrations: Migrations{requires(15, "000015.sql", 20), requires(20, "000020.sql")},
+			wantErr:    "migration 000015.sql is blocked: 000015.sql requires 000020.sql, which has a higher version and has not been applied",
+		},
+		"chain": {
+			migrations: Migrations{requires(10, "000010.sql", 5), requires(15, "000015.sql", 10), requires(30, "000030.sql", 15)},
+			wantErr:    "migration 000030.sql is blocked: 000030.sql requires 000015.sql requires 000010.sql requires version 5, which has not been applied and has no migration file",
+		},
+		"chain from dependent": {
+			migrations: Migrations{requires(10, "000010.sql"), requires(15, "000015.sql", 10, 5), requires(30, "000030.sql", 15)},
+			history:    []MigrationHistoryRecord{{Version: 10}, {Version: 15}},
+		},
+		"skipped": {
+			migrations: Migrations{requires(10, "000010.sql"), requires(15, "000015.sql", 10)},
+			history:    []MigrationHistoryRecord{{Version: 10, SkipReason: spanner.NullString{StringVal: SkipReasonEnvironment, Valid: true}}},
+			wantErr:    "migration 000015.sql is blocked: 000015.sql requires version 10, which was skipped (environment)",
+		},
+		"transitive": {
+			migrations: Migrations{
+				{Version: 10, FileName: "000010.sql", SkipReason: SkipReasonEmulator},
+				requires(15, "000015.sql", 10),
+				requires(30, "000030.sql", 15),
+			},
+			wantErr: "migration 000030.sql is blocked: 000030.sql requires 000015.sql requires 000010.sql, which will be skipped (emulator)",
+		},
+		"self": {
+			migrations: Migrations{requires(10, "000010.sql", 10)},
+			wantErr:    "migration 000010.sql is blocked: 000010.sql requires 000010.sql, which is a cycle",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkRequires(tt.migrations, tt.history)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseOutOfOrderPolicy(t *testing.T) {
+	p, err := ParseOutOfOrderPolicy("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, OutOfOrderWarn, p)
+
+	_, err = ParseOutOfOrderPolicy("sometimes")
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy configures how idempotent operations are retried after a transient error.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts. Retries are disabled if less than 2.
+	Attempts int
+	// Backoff is the initial delay between attempts, which doubles after each attempt.
+	Backoff time.Duration
+}
+
+// isTransient returns true for errors that are expected to succeed if the request is retried.
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// retry calls f until it succeeds, returns an error that is not transient, or the attempts are exhausted. f must be
+// idempotent.
+func retry(ctx context.Context, policy RetryPolicy, f func(ctx context.Context) error) error {
+	backoff := gax.Backoff{
+		Initial:    policy.Backoff,
+		Max:        32 * policy.Backoff,
+		Multiplier: 2,
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		// the context deadline is not transient, e.g. a migration timeout
+		if err == nil || attempt >= policy.Attempts || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "retrying after transient error (attempt %d of %d): %v\n", attempt, policy.Attempts, err)
+		if err := gax.Sleep(ctx, backoff.Pause()); err != nil {
+			return err
+		}
+	}
+}
+
+// retry retries f with the retry policy of the client.
+func (c *Client) retry(ctx context.Context, f func(ctx context.Context) error) error {
+	return retry(ctx, c.config.RetryPolicy, f)
+}
@@ -0,0 +1,108 @@
+package spanner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_retry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "Success",
+			policy:    policy,
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "TransientThenSuccess",
+			policy:    policy,
+			errs:      []error{status.Error(codes.Unavailable, "unavailable"), status.Error(codes.DeadlineExceeded, "deadline"), nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "WrappedTransient",
+			policy:    policy,
+			errs:      []error{&Error{Code: ErrorCodeSetMigrationVersion, err: status.Error(codes.Unavailable, "unavailable")}, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "AttemptsExhausted",
+			policy:    policy,
+			errs:      []error{status.Error(codes.Unavailable, "1"), status.Error(codes.Unavailable, "2"), status.Error(codes.Unavailable, "3")},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "NotTransient",
+			policy:    policy,
+			errs:      []error{status.Error(codes.InvalidArgument, "invalid")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "NotStatus",
+			policy:    policy,
+			errs:      []error{errors.New("boom")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "Disabled",
+			policy:    RetryPolicy{},
+			errs:      []error{status.Error(codes.Unavailable, "unavailable")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			err := retry(context.Background(), tt.policy, func(ctx context.Context) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("ContextDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var calls int
+		err := retry(ctx, policy, func(ctx context.Context) error {
+			calls++
+			return status.Error(codes.DeadlineExceeded, "deadline")
+		})
+
+		assert.Equal(t, 1, calls)
+		assert.Error(t, err)
+	})
+}
+
+func Test_ddlOperationID(t *testing.T) {
+	id := ddlOperationID()
+	assert.Regexp(t, `^[a-z][a-z0-9_]*$`, id)
+	assert.NotEqual(t, id, ddlOperationID())
+}
@@ -0,0 +1,107 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	databasepb "cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+// snapshotBatchSize is the number of rows inserted in each transaction when a database is created from a snapshot.
+const snapshotBatchSize = 500
+
+// Snapshot is the schema of a database with the data of some of its tables, used to create a shadow database that
+// rehearses migrations.
+type Snapshot struct {
+	DDL              []string
+	ProtoDescriptors []byte
+	Data             []StaticData
+}
+
+// LoadSnapshot loads the schema and proto descriptors of the database, the data of the migration tracking tables of
+// versionTableName that exist, and the data of the given tables. The lock table is created without its data, so its
+// row must be set up with SetupMigrationLock before migrating the new database.
+func (c *Client) LoadSnapshot(ctx context.Context, versionTableName string, tables []string, customSort map[string]string) (*Snapshot, error) {
+	req := &databasepb.GetDatabaseDdlRequest{Database: c.config.URL()}
+	res, err := c.spannerAdminClient.GetDatabaseDdl(ctx, req)
+	if err != nil {
+		return nil, &Error{
+			Code: ErrorCodeLoadSchema,
+			err:  err,
+		}
+	}
+
+	var existing []string
+	for _, s := range res.Statements {
+		if matches := createTableNameRegex.FindStringSubmatch(s); matches != nil {
+			existing = append(existing, matches[1])
+		}
+	}
+
+	trackingTables := []string{
+		versionTableName,
+		versionTableName + historyStr,
+		RepeatableHistoryTableName(versionTableName),
+		SeedHistoryTableName(versionTableName),
+		upgradeIndicator,
+	}
+	var dataTables []string
+	for _, t := range append(trackingTables, tables...) {
+		if slices.Contains(existing, t) && !slices.Contains(dataTables, t) {
+			dataTables = append(dataTables, t)
+		}
+	}
+	for _, t := range tables {
+		if !slices.Contains(existing, t) {
+			return nil, fmt.Errorf("static data table %s does not exist", t)
+		}
+	}
+
+	data, err := c.LoadStaticDatas(ctx, dataTables, customSort)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		DDL:              res.Statements,
+		ProtoDescriptors: res.ProtoDescriptors,
+		Data:             data,
+	}, nil
+}
+
+// CreateDatabaseFromSnapshot creates the database with the schema of the snapshot, then inserts the data of the
+// snapshot.
+func (c *Client) CreateDatabaseFromSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	var ddl strings.Builder
+	for _, s := range snapshot.DDL {
+		ddl.WriteString(s)
+		ddl.WriteString(";\n")
+	}
+	if err := c.CreateDatabase(ctx, []byte(ddl.String()), snapshot.ProtoDescriptors); err != nil {
+		return err
+	}
+
+	for _, data := range snapshot.Data {
+		for batch := range slices.Chunk(data.Statements, snapshotBatchSize) {
+			_, err := c.spannerClient.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+				stmts := make([]spanner.Statement, 0, len(batch))
+				for _, s := range batch {
+					stmts = append(stmts, spanner.NewStatement(s))
+				}
+				_, err := tx.BatchUpdate(ctx, stmts)
+				return err
+			})
+			if err != nil {
+				return &Error{
+					Code: ErrorCodeCreateDatabase,
+					err:  fmt.Errorf("failed to insert the data of %s: %w", data.TableName, err),
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package spanner
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDatabaseFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	client, done := testClientWithDatabase(t, ctx)
+	defer done()
+
+	_, err := client.spannerClient.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert(migrationTable, []string{"Version", "Dirty"}, []interface{}{3, false}),
+		spanner.Insert("Singers", []string{"SingerID", "FirstName"}, []interface{}{"1", "Ella"}),
+	})
+	require.NoError(t, err)
+
+	snapshot, err := client.LoadSnapshot(ctx, migrationTable, []string{"Singers"}, nil)
+	require.NoError(t, err)
+
+	_, err = client.LoadSnapshot(ctx, migrationTable, []string{"Albums"}, nil)
+	assert.ErrorContains(t, err, "static data table Albums does not exist")
+
+	config := *client.config
+	config.Database += "-shadow"
+	shadow, err := NewClient(ctx, &config)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, shadow.DropDatabase(ctx))
+		shadow.Close()
+	}()
+
+	require.NoError(t, shadow.CreateDatabaseFromSnapshot(ctx, snapshot))
+
+	gotDDL, err := shadow.LoadDDL(ctx)
+	require.NoError(t, err)
+	wantDDL, err := client.LoadDDL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, string(wantDDL), string(gotDDL))
+
+	version, dirty, err := shadow.GetSchemaMigrationVersion(ctx, migrationTable)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), version)
+	assert.False(t, dirty)
+
+	row, err := shadow.spannerClient.Single().ReadRow(ctx, "Singers", spanner.Key{"1"}, []string{"FirstName"})
+	require.NoError(t, err)
+	var firstName string
+	require.NoError(t, row.Columns(&firstName))
+	assert.Equal(t, "Ella", firstName)
+}
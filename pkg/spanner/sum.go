@@ -0,0 +1,194 @@
+package spanner
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SumFileName is the name of the manifest of migration files in the migrations directory.
+const SumFileName = "wrench.sum"
+
+type (
+	// SumEntry is the hash of the contents of a migration file.
+	SumEntry struct {
+		FileName string
+		Hash     string
+		// Version is the version of a versioned migration, or 0 for repeatable migrations and seeds.
+		Version uint
+	}
+
+	// Sum is the manifest of migration files, ordered by version then by the names of repeatable migrations and seeds.
+	// Two branches that add the same version both change the same line of the manifest, so the collision shows up as
+	// a merge conflict instead of a duplicate or out-of-order migration.
+	Sum []SumEntry
+)
+
+// ComputeSum hashes the versioned migrations, repeatable migrations and seeds in dir and its subdirectories. The
+// companion assert file and emulator replacement file of a migration are hashed with the migration, as they change
+// what it does. dir may be a list of directories joined by os.PathListSeparator, in which case the file names are
+// relative to the first directory, where the sum file is written.
+func ComputeSum(dir string) (Sum, error) {
+	files, err := ListMigrationFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sum Sum
+	for _, f := range files {
+		version, ok := MigrationVersion(f.FileName)
+		baseName := path.Base(f.FileName)
+		if !ok && !repeatableMigrationRegex.MatchString(baseName) && !seedRegex.MatchString(baseName) {
+			continue
+		}
+
+		file, err := os.ReadFile(f.Path())
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		hash.Write(normalizeLineEndings(file))
+		for _, companion := range companionFiles(f, file) {
+			content, err := os.ReadFile(companion)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(hash, "\n-- %s\n", filepath.Base(companion))
+			hash.Write(normalizeLineEndings(content))
+		}
+
+		fileName := f.FileName
+		if root := MigrationsDirs(dir)[0]; f.Dir != root {
+			rel, err := filepath.Rel(root, f.Path())
+			if err != nil {
+				return nil, err
+			}
+			fileName = filepath.ToSlash(rel)
+		}
+
+		sum = append(sum, SumEntry{
+			FileName: fileName,
+			Hash:     "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+			Version:  version,
+		})
+	}
+
+	slices.SortFunc(sum, func(a, b SumEntry) int {
+		switch {
+		case (a.Version == 0) != (b.Version == 0):
+			// versioned migrations first
+			if a.Version == 0 {
+				return 1
+			}
+			return -1
+		case a.Version != b.Version:
+			return cmp.Compare(a.Version, b.Version)
+		default:
+			return strings.Compare(a.FileName, b.FileName)
+		}
+	})
+
+	return sum, nil
+}
+
+// normalizeLineEndings normalizes line endings to LF to ensure consistent hashes across platforms.
+func normalizeLineEndings(file []byte) []byte {
+	return bytes.ReplaceAll(file, []byte("\r\n"), []byte("\n"))
+}
+
+// companionFiles returns the paths of the assert file and the emulator replacement file of the migration, which may
+// not exist.
+func companionFiles(f MigrationFile, file []byte) []string {
+	paths := []string{filepath.Join(f.Dir, filepath.FromSlash(AssertFileName(f.FileName)))}
+	// invalid directives are reported when the migrations are loaded
+	if directives, err := parseMigrationDirectives(string(file)); err == nil && directives.EmulatorReplacement != "" {
+		paths = append(paths, filepath.Join(filepath.Dir(f.Path()), directives.EmulatorReplacement))
+	}
+	return paths
+}
+
+// ReadSum reads the sum file in dir, or in the first directory of a list of directories.
+func ReadSum(dir string) (Sum, error) {
+	file, err := os.ReadFile(filepath.Join(MigrationsDirs(dir)[0], SumFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var sum Sum
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "<<<<<<<") || strings.HasPrefix(text, "=======") || strings.HasPrefix(text, ">>>>>>>") {
+			return nil, fmt.Errorf("%s has merge conflicts, two branches may have added the same migration version. Renumber the migrations then run migrate sum", SumFileName)
+		}
+
+		fileName, hash, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: invalid line %q", SumFileName, line, text)
+		}
+		sum = append(sum, SumEntry{FileName: fileName, Hash: strings.TrimSpace(hash)})
+	}
+
+	return sum, scanner.Err()
+}
+
+// Write writes the sum to the sum file in dir, or in the first directory of a list of directories.
+func (s Sum) Write(dir string) error {
+	var b strings.Builder
+	for _, e := range s {
+		fmt.Fprintf(&b, "%s %s\n", e.FileName, e.Hash)
+	}
+	return os.WriteFile(filepath.Join(MigrationsDirs(dir)[0], SumFileName), []byte(b.String()), 0o644)
+}
+
+// Verify returns an error listing the files that were added, edited or deleted without updating the recorded sum, and
+// the versions that are used by more than one file.
+func (s Sum) Verify(recorded Sum) error {
+	var problems []string
+
+	for i := 1; i < len(s); i++ {
+		if s[i].Version != 0 && s[i].Version == s[i-1].Version {
+			problems = append(problems, fmt.Sprintf("version %d is used by both %s and %s", s[i].Version, s[i-1].FileName, s[i].FileName))
+		}
+	}
+
+	hashes := make(map[string]string, len(recorded))
+	for _, e := range recorded {
+		hashes[e.FileName] = e.Hash
+	}
+	for _, e := range s {
+		hash, ok := hashes[e.FileName]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s was added without updating %s", e.FileName, SumFileName))
+		case hash != e.Hash:
+			problems = append(problems, fmt.Sprintf("%s was edited without updating %s", e.FileName, SumFileName))
+		}
+		delete(hashes, e.FileName)
+	}
+	for _, e := range recorded {
+		if _, ok := hashes[e.FileName]; ok {
+			problems = append(problems, fmt.Sprintf("%s was deleted without updating %s", e.FileName, SumFileName))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "\n"))
+}
@@ -0,0 +1,128 @@
+package spanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeSum(t *testing.T) {
+	dir := t.TempDir()
+	newFile(t, dir, "000120_b.sql", []byte("SELECT 1;\n"))
+	newFile(t, dir, "000110_a.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "000120_a.sql", []byte("SELECT 1;\r\n"))
+	newFile(t, dir, "R__view.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "S__data.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "000110_a.assert.sql", []byte("SELECT true;"))
+	newFile(t, dir, "README.md", []byte("not a migration"))
+
+	sum, err := ComputeSum(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range sum {
+		names = append(names, e.FileName)
+	}
+	assert.Equal(t, []string{"000110_a.sql", "000120_a.sql", "000120_b.sql", "R__view.sql", "S__data.sql"}, names)
+	assert.Equal(t, sum[1].Hash, sum[2].Hash, "line endings should be normalized")
+	assert.Contains(t, sum[0].Hash, "sha256:")
+}
+
+func TestComputeSumSubdirectories(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	shared := filepath.Join(filepath.Dir(dir), "shared")
+	for _, d := range []string{filepath.Join(dir, "2026"), filepath.Join(dir, ArchiveDirName), shared} {
+		require.NoError(t, os.MkdirAll(d, os.ModePerm))
+	}
+	newFile(t, dir, "2026/000120_b.sql", []byte("SELECT 1;"))
+	newFile(t, dir, ArchiveDirName+"/000010_old.sql", []byte("SELECT 1;"))
+	newFile(t, shared, "000110_a.sql", []byte("SELECT 1;"))
+
+	sum, err := ComputeSum(dir + string(os.PathListSeparator) + shared)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range sum {
+		names = append(names, e.FileName)
+	}
+	assert.Equal(t, []string{"../shared/000110_a.sql", "2026/000120_b.sql"}, names)
+}
+
+func TestComputeSumCompanionFiles(t *testing.T) {
+	dir := t.TempDir()
+	newFile(t, dir, "000110_a.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "000120_b.sql", []byte("-- @wrench.EmulatorReplacement=000120_b.emulator.sql\nSELECT 1;"))
+	newFile(t, dir, "000120_b.emulator.sql", []byte("SELECT 2;"))
+
+	recorded, err := ComputeSum(dir)
+	require.NoError(t, err)
+	require.Len(t, recorded, 2)
+
+	// adding or editing an assert file changes the hash of its migration
+	newFile(t, dir, "000110_a.assert.sql", []byte("SELECT true;"))
+	sum, err := ComputeSum(dir)
+	require.NoError(t, err)
+	assert.EqualError(t, sum.Verify(recorded), "000110_a.sql was edited without updating wrench.sum")
+
+	recorded = sum
+	newFile(t, dir, "000110_a.assert.sql", []byte("SELECT false;"))
+	sum, err = ComputeSum(dir)
+	require.NoError(t, err)
+	assert.EqualError(t, sum.Verify(recorded), "000110_a.sql was edited without updating wrench.sum")
+
+	// editing an emulator replacement changes the hash of its migration
+	recorded = sum
+	newFile(t, dir, "000120_b.emulator.sql", []byte("SELECT 3;"))
+	sum, err = ComputeSum(dir)
+	require.NoError(t, err)
+	assert.EqualError(t, sum.Verify(recorded), "000120_b.sql was edited without updating wrench.sum")
+}
+
+func TestSumVerify(t *testing.T) {
+	dir := t.TempDir()
+	newFile(t, dir, "000110_a.sql", []byte("SELECT 1;"))
+	newFile(t, dir, "000120_b.sql", []byte("SELECT 1;"))
+
+	sum, err := ComputeSum(dir)
+	require.NoError(t, err)
+	require.NoError(t, sum.Write(dir))
+
+	recorded, err := ReadSum(dir)
+	require.NoError(t, err)
+	require.NoError(t, sum.Verify(recorded))
+
+	// the other branch's migration is merged without updating the sum
+	newFile(t, dir, "000120_c.sql", []byte("SELECT 2;"))
+	newFile(t, dir, "000110_a.sql", []byte("SELECT 3;"))
+	require.NoError(t, os.Remove(filepath.Join(dir, "000120_b.sql")))
+	newFile(t, dir, "000130_d.sql", []byte("SELECT 4;"))
+	newFile(t, dir, "000130_e.sql", []byte("SELECT 5;"))
+
+	sum, err = ComputeSum(dir)
+	require.NoError(t, err)
+	err = sum.Verify(recorded)
+	require.Error(t, err)
+	assert.Equal(t, `version 130 is used by both 000130_d.sql and 000130_e.sql
+000110_a.sql was edited without updating wrench.sum
+000120_c.sql was added without updating wrench.sum
+000130_d.sql was added without updating wrench.sum
+000130_e.sql was added without updating wrench.sum
+000120_b.sql was deleted without updating wrench.sum`, err.Error())
+}
+
+func TestReadSumMergeConflict(t *testing.T) {
+	dir := t.TempDir()
+	newFile(t, dir, SumFileName, []byte(`000110_a.sql sha256:aa
+<<<<<<< HEAD
+000120_b.sql sha256:bb
+=======
+000120_c.sql sha256:cc
+>>>>>>> feature
+`))
+
+	_, err := ReadSum(dir)
+	assert.ErrorContains(t, err, "wrench.sum has merge conflicts")
+}
@@ -0,0 +1,92 @@
+package spanner
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const templateSuffix = ".tmpl.sql"
+
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// templateFuncs is the function library available to migration templates, in addition to the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"seq": func(start, end int) []int {
+		var s []int
+		for i := start; i <= end; i++ {
+			s = append(s, i)
+		}
+		return s
+	},
+	"add": func(a, b int) int { return a + b },
+	// int converts a placeholder to an int. An empty string is 0, e.g. when placeholder replacement is disabled
+	"int": func(s string) (int, error) {
+		if s == "" {
+			return 0, nil
+		}
+		return strconv.Atoi(s)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"split": func(s, sep string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, sep)
+	},
+	"join": func(sep string, s []string) string { return strings.Join(s, sep) },
+	"default": func(def, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+	// quote returns a GoogleSQL string literal
+	"quote": func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	},
+	// ident returns a quoted GoogleSQL identifier. Names that are not valid identifiers are rejected, so that a
+	// placeholder cannot break out of the quotes.
+	"ident": func(s string) (string, error) {
+		if !identifierRegex.MatchString(s) {
+			return "", fmt.Errorf("invalid identifier %q", s)
+		}
+		return "`" + s + "`", nil
+	},
+}
+
+// isTemplate returns true if the migration file is rendered with text/template, e.g. 001_name.tmpl.sql.
+func isTemplate(fileName string) bool {
+	return strings.HasSuffix(strings.ToLower(fileName), templateSuffix)
+}
+
+// renderTemplate renders the migration file with text/template. The placeholders are the data of the template, and it
+// is an error to refer to a placeholder that is not defined when placeholder replacement is enabled.
+func renderTemplate(fileName string, file []byte, placeholderOptions PlaceholderOptions) ([]byte, error) {
+	missingKey := "missingkey=zero"
+	if placeholderOptions.ReplacementEnabled {
+		missingKey = "missingkey=error"
+	}
+
+	tmpl, err := template.New(fileName).Option(missingKey).Funcs(templateFuncs).Parse(string(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	data := placeholderOptions.Placeholders
+	if data == nil {
+		data = map[string]string{}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
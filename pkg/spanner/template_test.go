@@ -0,0 +1,58 @@
+package spanner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_renderTemplate(t *testing.T) {
+	tests := map[string]struct {
+		template           string
+		placeholders       map[string]string
+		replacementEnabled bool
+		want               string
+		wantErr            bool
+	}{
+		"functions": {
+			template:           `{{ range $i := seq 1 (int .N) }}{{ add $i 10 }} {{ end }}{{ join "," (split .LIST ";") }} {{ quote .NAME }} {{ ident (upper .TABLE) }} {{ default "x" .EMPTY }}`,
+			placeholders:       map[string]string{"N": "2", "LIST": "a;b", "NAME": `it's`, "TABLE": "singers", "EMPTY": ""},
+			replacementEnabled: true,
+			want:               "11 12 a,b 'it\\'s' `SINGERS` x",
+		},
+		"missing placeholder is an error when replacement is enabled": {
+			template:           `SELECT {{ .MISSING }}`,
+			replacementEnabled: true,
+			wantErr:            true,
+		},
+		"missing placeholder is empty when replacement is disabled": {
+			template: `SELECT 1{{ .MISSING }}`,
+			want:     "SELECT 1",
+		},
+		"invalid identifier": {
+			template:           "SELECT 1 FROM {{ ident .TABLE }}",
+			placeholders:       map[string]string{"TABLE": "Singers` WHERE 1=1 --"},
+			replacementEnabled: true,
+			wantErr:            true,
+		},
+		"parse error": {
+			template: `{{ if }}`,
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := renderTemplate("000001_test.tmpl.sql", []byte(tt.template), PlaceholderOptions{
+				Placeholders:       tt.placeholders,
+				ReplacementEnabled: tt.replacementEnabled,
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
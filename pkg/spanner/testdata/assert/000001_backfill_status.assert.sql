@@ -0,0 +1,3 @@
+SELECT SingerID FROM Singers WHERE FirstName IS NULL;
+
+SELECT COUNT(*) > 0 FROM Singers;
@@ -0,0 +1,4 @@
+/*
+ @wrench.Assert=SELECT COUNT(*) = 0 FROM Singers WHERE FirstName IS NULL
+*/
+UPDATE Singers SET FirstName = "" WHERE FirstName IS NULL;
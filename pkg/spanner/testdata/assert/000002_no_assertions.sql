@@ -0,0 +1 @@
+UPDATE Singers SET FirstName = "" WHERE FirstName IS NULL;
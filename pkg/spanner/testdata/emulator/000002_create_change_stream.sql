@@ -0,0 +1,2 @@
+-- @wrench.SkipOnEmulator
+CREATE CHANGE STREAM SingersStream FOR Singers OPTIONS (exclude_ttl_deletes = true);
@@ -0,0 +1 @@
+CREATE INDEX SingersByFirstName ON Singers(FirstName);
@@ -0,0 +1,2 @@
+-- @wrench.EmulatorReplacement=000003_create_search_index.emulator.sql
+CREATE SEARCH INDEX SingersByFirstName ON Singers(FirstNameTokens);
@@ -17,4 +17,8 @@ CREATE TABLE SchemaMigrationsHistory (
   Modified TIMESTAMP NOT NULL OPTIONS (
     allow_commit_timestamp = true
   ),
+  OperationName STRING(MAX),
+  CommittedStatements INT64,
+  FailureMessage STRING(MAX),
+  SkipReason STRING(MAX),
 ) PRIMARY KEY(Version);
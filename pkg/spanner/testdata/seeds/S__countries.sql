@@ -0,0 +1 @@
+INSERT OR UPDATE INTO Singers (SingerID, FirstName) VALUES ('${SINGER_ID:-1}', 'Country');
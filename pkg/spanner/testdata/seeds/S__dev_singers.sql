@@ -0,0 +1,2 @@
+-- @wrench.Environments=dev
+INSERT OR UPDATE INTO Singers (SingerID, FirstName) VALUES ('dev-1', 'Dev');
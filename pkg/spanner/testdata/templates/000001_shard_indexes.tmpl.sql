@@ -0,0 +1,6 @@
+{{- range $i := seq 1 (int .SHARDS) }}
+CREATE INDEX Orders_{{ $i }}_ByCustomer ON Orders_{{ $i }}(CustomerID);
+{{- end }}
+{{- if eq .MULTI_REGION "true" }}
+ALTER DATABASE db SET OPTIONS (default_leader = {{ quote .LEADER }});
+{{- end }}
@@ -0,0 +1 @@
+CREATE TABLE Orders (OrderID INT64 NOT NULL) PRIMARY KEY (OrderID);